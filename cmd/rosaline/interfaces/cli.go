@@ -2,19 +2,24 @@ package interfaces
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"os"
 	"rosaline/internal/chess"
 	"rosaline/internal/evaluation"
 	"rosaline/internal/perft"
+	"rosaline/internal/pgn"
 	"rosaline/internal/search"
 	"rosaline/internal/utils"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type cliInterface struct {
-	searcher  search.NegamaxSearcher
+	searcher  *search.NegamaxSearcher
 	evaluator evaluation.Evaluator
 }
 
@@ -26,11 +31,90 @@ func NewCliProtocolHandler() cliInterface {
 	}
 }
 
+// parseCliGoArgs parses the search limits and the number of lines to search
+// for from the arguments of the go command.
+func parseCliGoArgs(args []string, position chess.Position) (search.SearchLimits, int, error) {
+	limits := search.NewDepthLimits(DefaultDepth)
+	multiPV := 1
+
+	for index := 0; index < len(args); index++ {
+		switch args[index] {
+		case "multipv":
+			if index+1 >= len(args) {
+				return limits, 0, errors.New("multipv requires the number of lines as an argument")
+			}
+
+			index++
+
+			var err error
+			multiPV, err = strconv.Atoi(args[index])
+			if err != nil {
+				return limits, 0, fmt.Errorf("invalid number of lines: %s", args[index])
+			}
+			break
+		case "searchmoves":
+			moves, count, err := parseSearchMoves(args[index+1:], position)
+			if err != nil {
+				return limits, 0, err
+			}
+
+			limits.SearchMoves = moves
+			index += count
+			break
+		default:
+			var err error
+			limits.Depth, err = strconv.Atoi(args[index])
+			if err != nil {
+				return limits, 0, fmt.Errorf("invalid depth: %s", args[index])
+			}
+			break
+		}
+	}
+
+	return limits, multiPV, nil
+}
+
+// writeCliPgn writes the game made up of the moves played from the starting
+// position as pgn, either to the file given in the arguments or to stdout.
+func writeCliPgn(args []string, startingFen string, chess960 bool, moves []chess.Move) error {
+	game, err := chess.NewGame(startingFen)
+	if err != nil {
+		return err
+	}
+
+	game.Position.SetChess960(chess960)
+	game.SetTag("Event", "rosaline cli game")
+	game.SetTag("Date", time.Now().Format("2006.01.02"))
+
+	for _, move := range moves {
+		err := game.MakeMove(move)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(args) < 1 {
+		return pgn.Write(os.Stdout, game)
+	}
+
+	file, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return pgn.Write(file, game)
+}
+
 func (i cliInterface) Loop() {
 	scanner := bufio.NewScanner(os.Stdin)
 
 	position, _ := chess.NewPosition(chess.StartingFen)
 
+	// the moves played since the position was set so they can be exported as pgn
+	startingFen := chess.StartingFen
+	played := []chess.Move{}
+
 	for {
 		fmt.Print(position.Turn())
 		fmt.Print("> ")
@@ -57,41 +141,100 @@ func (i cliInterface) Loop() {
 		} else if cmd == "moves" {
 			moves := position.GenerateMoves(chess.LegalMoveGeneration)
 			for _, move := range moves {
-				fmt.Printf("%s ", move)
+				fmt.Printf("%s ", move.Uci(position.IsChess960()))
 			}
 			fmt.Println()
 		} else if cmd == "move" {
 			if len(args) < 1 {
-				fmt.Println("move requires a uci formated move as an argument")
+				fmt.Println("move requires a uci or san formated move as an argument")
 				continue
 			}
 
-			err := position.MakeUciMove(args[0])
-			if err != nil {
-				fmt.Println(err)
-			}
-		} else if cmd == "undo" {
-			position.Undo()
-		} else if cmd == "go" {
-			depth := DefaultDepth
-			if len(args) >= 1 {
+			// moves that aren't legal uci moves are read as san
+			move, ok := findUciMove(position, args[0])
+			if !ok {
 				var err error
-				depth, err = strconv.Atoi(args[0])
+				move, err = position.ParseSan(args[0])
 				if err != nil {
 					fmt.Println(err)
 					continue
 				}
 			}
 
-			bestMove := i.searcher.Search(position, depth, false)
-			fmt.Println("best move:", bestMove)
+			position.MakeMove(move)
+			played = append(played, move)
+		} else if cmd == "undo" {
+			if !position.CanUndo() {
+				fmt.Println("there are no moves to undo")
+				continue
+			}
+
+			position.Undo()
+
+			// undoing a move made before the game was last restarted, such as by switch,
+			// restarts the game from the position the undo went back to
+			if len(played) > 0 {
+				played = played[:len(played)-1]
+			} else {
+				startingFen = position.Fen()
+			}
+		} else if cmd == "go" {
+			limits, multiPV, err := parseCliGoArgs(args, position)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			i.searcher.SetMultiPV(multiPV)
+			bestMove := i.searcher.Search(context.Background(), position, limits, false)
+			i.searcher.SetMultiPV(1)
+
+			if multiPV > 1 {
+				for k, line := range i.searcher.Lines() {
+					fmt.Printf("%d: score: %d pv: %s\n", k+1, line.Score, line.Uci(position.IsChess960()))
+				}
+			}
+
+			fmt.Println("best move:", bestMove.Uci(position.IsChess960()))
+		} else if cmd == "mate" {
+			if len(args) < 1 {
+				fmt.Println("mate requires the number of moves as an argument")
+				continue
+			}
+
+			moves, err := strconv.Atoi(args[0])
+			if err != nil || moves < 1 {
+				fmt.Println("invalid number of moves:", args[0])
+				continue
+			}
+
+			checksOnly := len(args) > 1 && args[1] == "checks"
+
+			i.searcher.SetMateChecksOnly(checksOnly)
+			line, found := i.searcher.SearchMate(context.Background(), position, moves)
+			i.searcher.SetMateChecksOnly(false)
+
+			if found {
+				fmt.Printf("mate in %d: %s\n", (len(line.Moves)+1)/2, line.Uci(position.IsChess960()))
+			} else {
+				fmt.Println("no mate in", moves, "found")
+			}
 		} else if cmd == "evaluate" {
 			score := i.evaluator.Evaluate(&position)
 			fmt.Println("score:", score)
+
+			win, draw, loss := evaluation.WinDrawLoss(score, position.Plies())
+			fmt.Println("wdl:", win, draw, loss)
 		} else if cmd == "play" {
-			bestMove := i.searcher.Search(position, DefaultDepth, false)
+			bestMove := i.searcher.Search(context.Background(), position, search.NewDepthLimits(DefaultDepth), false)
+			if bestMove == chess.NullMove {
+				fmt.Println("there are no moves to play")
+				continue
+			}
+
 			position.MakeMove(bestMove)
-			fmt.Println("played:", bestMove)
+			played = append(played, bestMove)
+			fmt.Println("played:", bestMove.Uci(position.IsChess960()))
 		} else if cmd == "fen" {
 			fmt.Println(position.Fen())
 		} else if cmd == "setfen" {
@@ -113,19 +256,57 @@ func (i cliInterface) Loop() {
 
 			i.searcher.Reset()
 			position = p
+			startingFen = position.Fen()
+			played = []chess.Move{}
+		} else if cmd == "chess960" {
+			index := rand.Intn(chess.Chess960Positions)
+			if len(args) > 0 {
+				var err error
+				index, err = strconv.Atoi(args[0])
+				if err != nil {
+					fmt.Println("invalid chess960 index:", args[0])
+					continue
+				}
+			}
+
+			p, err := chess.NewChess960Position(index)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			i.searcher.Reset()
+			position = p
+			startingFen = position.Fen()
+			played = []chess.Move{}
+			fmt.Println("chess960 position", index)
 		} else if cmd == "switch" {
 			position.MakeNullMove()
+
+			// pgn can't record passing the turn so the game starts again from here
+			startingFen = position.Fen()
+			played = []chess.Move{}
+		} else if cmd == "pgn" {
+			err := writeCliPgn(args, startingFen, position.IsChess960(), played)
+			if err != nil {
+				fmt.Println(err)
+			}
 		} else if cmd == "help" {
 			fmt.Println("display                      displays the current position")
 			fmt.Println("fen                          displays the current positions fen")
 			fmt.Println("setfen [fen | startpos]      changes the position to the given fen")
+			fmt.Println("chess960 [index]             changes the position to the given or a random chess960 starting position")
 			fmt.Println("perft [depth]                runs move generation test code to the specified depth")
 			fmt.Println("moves                        displays the legal moves for the current position")
-			fmt.Println("move [uci]                   make the given uci formatted move")
+			fmt.Println("move [uci | san]             make the given uci or san formatted move")
 			fmt.Println("switch                       passes turn to the opponent")
+			fmt.Println("pgn [file]                   writes the moves played since the position was set as pgn")
 			fmt.Println("undo                         undos the last move")
-			fmt.Println("go                           searches for the best move in the current position")
-			fmt.Println("evaluate                     evaluates the current position")
+			fmt.Println("go [depth] [multipv n] [searchmoves moves]")
+			fmt.Println("                             searches for the best move in the current position, optionally only")
+			fmt.Println("                             considering the given moves")
+			fmt.Println("mate [moves] [checks]        searches for a forced mate, optionally only trying checking moves")
+			fmt.Println("evaluate                     evaluates the current position and its win/draw/loss permille for white")
 			fmt.Println("play                         finds and plays the best move")
 			fmt.Println("help                         displays this message")
 			fmt.Println("quit                         exits the program")
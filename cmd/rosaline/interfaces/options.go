@@ -0,0 +1,269 @@
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type optionType uint8
+
+const (
+	checkOption optionType = iota
+	spinOption
+	comboOption
+	buttonOption
+	stringOption
+)
+
+func (t optionType) String() string {
+	switch t {
+	case checkOption:
+		return "check"
+	case spinOption:
+		return "spin"
+	case comboOption:
+		return "combo"
+	case buttonOption:
+		return "button"
+	case stringOption:
+		return "string"
+	}
+
+	panic(fmt.Sprintf("unknown optionType '%d' encountered", t))
+}
+
+// option is an engine setting that can be changed by the gui.
+type option struct {
+	name         string
+	optionType   optionType
+	defaultValue string
+	min          int      // The minimum value of a spin option.
+	max          int      // The maximum value of a spin option.
+	vars         []string // The allowed values of a combo option.
+
+	value string // The current value of the option.
+
+	onChange func(value string) // Called after the value has been validated and changed.
+}
+
+// newCheckOption creates a check option which can either be true or false.
+func newCheckOption(name string, defaultValue bool, onChange func(bool)) *option {
+	return &option{
+		name:         name,
+		optionType:   checkOption,
+		defaultValue: strconv.FormatBool(defaultValue),
+		value:        strconv.FormatBool(defaultValue),
+		onChange: func(value string) {
+			if onChange != nil {
+				onChange(value == "true")
+			}
+		},
+	}
+}
+
+// newSpinOption creates a spin option which is an integer within the given bounds.
+func newSpinOption(name string, defaultValue, min, max int, onChange func(int)) *option {
+	return &option{
+		name:         name,
+		optionType:   spinOption,
+		defaultValue: strconv.Itoa(defaultValue),
+		min:          min,
+		max:          max,
+		value:        strconv.Itoa(defaultValue),
+		onChange: func(value string) {
+			if onChange != nil {
+				number, _ := strconv.Atoi(value)
+				onChange(number)
+			}
+		},
+	}
+}
+
+// newComboOption creates a combo option which is one of a predefined set of values.
+func newComboOption(name string, defaultValue string, vars []string, onChange func(string)) *option {
+	return &option{
+		name:         name,
+		optionType:   comboOption,
+		defaultValue: defaultValue,
+		vars:         vars,
+		value:        defaultValue,
+		onChange:     onChange,
+	}
+}
+
+// newButtonOption creates a button option which performs an action when pressed.
+func newButtonOption(name string, onPress func()) *option {
+	return &option{
+		name:       name,
+		optionType: buttonOption,
+		onChange: func(string) {
+			if onPress != nil {
+				onPress()
+			}
+		},
+	}
+}
+
+// newStringOption creates a string option which can hold any text.
+func newStringOption(name string, defaultValue string, onChange func(string)) *option {
+	return &option{
+		name:         name,
+		optionType:   stringOption,
+		defaultValue: defaultValue,
+		value:        defaultValue,
+		onChange:     onChange,
+	}
+}
+
+// set validates and changes the value of the option.
+func (o *option) set(value string) error {
+	switch o.optionType {
+	case checkOption:
+		value = strings.ToLower(value)
+		if value != "true" && value != "false" {
+			return fmt.Errorf("invalid value '%s' for check option %s", value, o.name)
+		}
+		break
+	case spinOption:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s' for spin option %s", value, o.name)
+		}
+
+		if number < o.min || number > o.max {
+			return fmt.Errorf("value %d for %s is outside of the range %d to %d", number, o.name, o.min, o.max)
+		}
+		break
+	case comboOption:
+		index := slices.IndexFunc(o.vars, func(v string) bool {
+			return strings.EqualFold(v, value)
+		})
+
+		if index == -1 {
+			return fmt.Errorf("invalid value '%s' for combo option %s", value, o.name)
+		}
+
+		value = o.vars[index]
+		break
+	case stringOption:
+		if value == "<empty>" {
+			value = ""
+		}
+		break
+	}
+
+	o.value = value
+
+	if o.onChange != nil {
+		o.onChange(value)
+	}
+
+	return nil
+}
+
+// Int returns the value of a spin option.
+func (o option) Int() int {
+	number, _ := strconv.Atoi(o.value)
+	return number
+}
+
+// Bool returns the value of a check option.
+func (o option) Bool() bool {
+	return o.value == "true"
+}
+
+// String returns the option formatted for the uci command's reply.
+func (o option) String() string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("option name %s type %s", o.name, o.optionType))
+
+	switch o.optionType {
+	case checkOption, comboOption:
+		builder.WriteString(fmt.Sprintf(" default %s", o.defaultValue))
+		break
+	case spinOption:
+		builder.WriteString(fmt.Sprintf(" default %s min %d max %d", o.defaultValue, o.min, o.max))
+		break
+	case stringOption:
+		defaultValue := o.defaultValue
+		if defaultValue == "" {
+			defaultValue = "<empty>"
+		}
+
+		builder.WriteString(fmt.Sprintf(" default %s", defaultValue))
+		break
+	}
+
+	for _, v := range o.vars {
+		builder.WriteString(fmt.Sprintf(" var %s", v))
+	}
+
+	return builder.String()
+}
+
+// optionRegistry holds the options an engine supports in the order they were registered.
+type optionRegistry struct {
+	options []*option
+}
+
+// newOptionRegistry creates an empty optionRegistry.
+func newOptionRegistry() optionRegistry {
+	return optionRegistry{
+		options: []*option{},
+	}
+}
+
+// register adds the option to the registry.
+func (r *optionRegistry) register(o *option) {
+	r.options = append(r.options, o)
+}
+
+// get retrieves the option with the given name. Option names are case insensitive.
+func (r optionRegistry) get(name string) (*option, bool) {
+	for _, o := range r.options {
+		if strings.EqualFold(o.name, name) {
+			return o, true
+		}
+	}
+
+	return nil, false
+}
+
+// set changes the value of the option with the given name.
+func (r *optionRegistry) set(name string, value string) error {
+	o, ok := r.get(name)
+	if !ok {
+		return fmt.Errorf("unknown option: %s", name)
+	}
+
+	return o.set(value)
+}
+
+// parseSetOption parses the name and value from the arguments of the setoption command.
+//
+// Both the name and value can contain spaces.
+func parseSetOption(args []string) (string, string, error) {
+	if len(args) < 2 || args[0] != "name" {
+		return "", "", errors.New("setoption requires a name")
+	}
+
+	args = args[1:]
+
+	valueIndex := slices.Index(args, "value")
+	if valueIndex == -1 {
+		return strings.Join(args, " "), "", nil
+	}
+
+	name := strings.Join(args[:valueIndex], " ")
+	if name == "" {
+		return "", "", errors.New("setoption requires a name")
+	}
+
+	value := strings.Join(args[valueIndex+1:], " ")
+
+	return name, value, nil
+}
@@ -0,0 +1,132 @@
+package interfaces
+
+import "testing"
+
+func parseSetOptionTest(t *testing.T, args []string, expectedName string, expectedValue string) {
+	name, value, err := parseSetOption(args)
+	if err != nil {
+		t.Fatalf("%s: %v returned an error: %v", t.Name(), args, err)
+	}
+
+	if name != expectedName || value != expectedValue {
+		t.Fatalf("%s: expected name '%s' and value '%s' got '%s' and '%s'", t.Name(), expectedName, expectedValue, name, value)
+	}
+}
+
+func TestParseSetOption(t *testing.T) {
+	parseSetOptionTest(t, []string{"name", "Hash", "value", "128"}, "Hash", "128")
+	parseSetOptionTest(t, []string{"name", "Clear", "Hash"}, "Clear Hash", "")
+	parseSetOptionTest(t, []string{"name", "Move", "Overhead", "value", "100"}, "Move Overhead", "100")
+	parseSetOptionTest(t, []string{"name", "Log", "value", "a", "b"}, "Log", "a b")
+
+	_, _, err := parseSetOption([]string{"Hash", "value", "128"})
+	if err == nil {
+		t.Fatalf("%s: expected an error for a missing name", t.Name())
+	}
+}
+
+func TestSpinOption(t *testing.T) {
+	changed := 0
+	registry := newOptionRegistry()
+	registry.register(newSpinOption("Hash", 64, 1, 1024, func(value int) {
+		changed = value
+	}))
+
+	err := registry.set("hash", "128")
+	if err != nil || changed != 128 {
+		t.Fatalf("%s: expected value to be changed to 128 got %d: %v", t.Name(), changed, err)
+	}
+
+	invalid := []string{"0", "1025", "abc"}
+	for _, value := range invalid {
+		err = registry.set("Hash", value)
+		if err == nil {
+			t.Fatalf("%s: expected an error for value '%s'", t.Name(), value)
+		}
+	}
+
+	option, _ := registry.get("Hash")
+	if option.Int() != 128 {
+		t.Fatalf("%s: expected invalid values to be ignored but value is %d", t.Name(), option.Int())
+	}
+}
+
+func TestCheckOption(t *testing.T) {
+	changed := false
+	registry := newOptionRegistry()
+	registry.register(newCheckOption("Ponder", false, func(value bool) {
+		changed = value
+	}))
+
+	err := registry.set("Ponder", "true")
+	if err != nil || !changed {
+		t.Fatalf("%s: expected value to be changed to true: %v", t.Name(), err)
+	}
+
+	err = registry.set("Ponder", "yes")
+	if err == nil {
+		t.Fatalf("%s: expected an error for an invalid value", t.Name())
+	}
+}
+
+func TestComboOption(t *testing.T) {
+	registry := newOptionRegistry()
+	registry.register(newComboOption("Style", "Normal", []string{"Solid", "Normal", "Risky"}, nil))
+
+	err := registry.set("Style", "risky")
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	option, _ := registry.get("Style")
+	if option.value != "Risky" {
+		t.Fatalf("%s: expected value to be 'Risky' got '%s'", t.Name(), option.value)
+	}
+
+	err = registry.set("Style", "Wild")
+	if err == nil {
+		t.Fatalf("%s: expected an error for an invalid value", t.Name())
+	}
+}
+
+func TestUnknownOption(t *testing.T) {
+	registry := newOptionRegistry()
+	err := registry.set("Unknown", "1")
+	if err == nil {
+		t.Fatalf("%s: expected an error for an unknown option", t.Name())
+	}
+}
+
+func TestOptionString(t *testing.T) {
+	cases := []struct {
+		Option   *option
+		Expected string
+	}{
+		{
+			Option:   newSpinOption("Hash", 64, 1, 4096, nil),
+			Expected: "option name Hash type spin default 64 min 1 max 4096",
+		},
+		{
+			Option:   newCheckOption("Ponder", false, nil),
+			Expected: "option name Ponder type check default false",
+		},
+		{
+			Option:   newButtonOption("Clear Hash", nil),
+			Expected: "option name Clear Hash type button",
+		},
+		{
+			Option:   newComboOption("Style", "Normal", []string{"Solid", "Normal"}, nil),
+			Expected: "option name Style type combo default Normal var Solid var Normal",
+		},
+		{
+			Option:   newStringOption("Log File", "", nil),
+			Expected: "option name Log File type string default <empty>",
+		},
+	}
+
+	for _, c := range cases {
+		if c.Option.String() != c.Expected {
+			t.Fatalf("%s: expected '%s' got '%s'", t.Name(), c.Expected, c.Option.String())
+		}
+	}
+}
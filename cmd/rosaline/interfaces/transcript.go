@@ -0,0 +1,79 @@
+package interfaces
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	transcriptTimeFormat = "2006-01-02 15:04:05.000"
+
+	transcriptInput  = ">>" // Marks lines received by the engine.
+	transcriptOutput = "<<" // Marks lines sent by the engine.
+)
+
+// transcript records every line sent to and received from the gui, each with
+// the time it was sent or received.
+type transcript struct {
+	mutex  sync.Mutex
+	writer io.WriteCloser
+	now    func() time.Time
+}
+
+// newTranscript creates a transcript that doesn't record anything until a
+// file is opened.
+func newTranscript() *transcript {
+	return &transcript{
+		now: time.Now,
+	}
+}
+
+// open starts recording to the file at the given path, appending to it if it
+// already exists. Any previously opened file is closed and an empty path
+// stops recording.
+func (t *transcript) open(path string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.writer != nil {
+		t.writer.Close()
+		t.writer = nil
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	t.writer = file
+
+	return nil
+}
+
+// close stops recording.
+func (t *transcript) close() error {
+	return t.open("")
+}
+
+// record writes each of the lines in text to the transcript with the given direction.
+func (t *transcript) record(direction string, text string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.writer == nil {
+		return
+	}
+
+	timestamp := t.now().Format(transcriptTimeFormat)
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		fmt.Fprintf(t.writer, "%s %s %s\n", timestamp, direction, line)
+	}
+}
@@ -2,67 +2,531 @@ package interfaces
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"rosaline/internal/chess"
 	"rosaline/internal/evaluation"
 	"rosaline/internal/search"
 	"rosaline/internal/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type uciInterface struct {
-	searcher  search.NegamaxSearcher
+	searcher  *search.NegamaxSearcher
 	evaluator evaluation.Evaluator
+	options   optionRegistry
+
+	input      io.Reader
+	output     io.Writer // Shared with the search so it has to be safe for concurrent use.
+	transcript *transcript
+
+	debug bool // Whether to send extra information to help debug the gui.
+
+	cancel   context.CancelFunc // Stops the running search.
+	finished chan struct{}      // Closed once the running search has found its best move.
+	done     chan struct{}      // Closed once the running search has written its best move.
+}
+
+func NewUciProtocolHandler() *uciInterface {
+	return newUciInterface(os.Stdin, os.Stdout)
 }
 
-func NewUciProtocolHandler() uciInterface {
+// newUciInterface creates a uciInterface that reads commands from input and
+// writes its replies to output.
+func newUciInterface(input io.Reader, output io.Writer) *uciInterface {
 	evaluator := evaluation.NewEvaluator()
-	return uciInterface{
-		searcher:  search.NewNegamaxSearcher(evaluator),
-		evaluator: evaluator,
+	transcript := newTranscript()
+	i := &uciInterface{
+		searcher:   search.NewNegamaxSearcher(evaluator),
+		evaluator:  evaluator,
+		options:    newOptionRegistry(),
+		input:      input,
+		output:     newLineWriter(output, transcript),
+		transcript: transcript,
+	}
+
+	i.searcher.SetOutput(i.output)
+	i.registerOptions()
+
+	return i
+}
+
+// registerOptions adds the options that can be changed by the gui.
+func (i *uciInterface) registerOptions() {
+	i.options.register(newSpinOption("Hash", search.DefaultTableSize, search.MinTableSize, search.MaxTableSize, func(size int) {
+		i.searcher.SetHashSize(size)
+	}))
+
+	i.options.register(newButtonOption("Clear Hash", func() {
+		i.searcher.ClearHash()
+	}))
+
+	// the gui decides when to ponder, the option only lets it know pondering is supported
+	i.options.register(newCheckOption("Ponder", false, nil))
+
+	// the search is single threaded so only one thread is supported
+	i.options.register(newSpinOption("Threads", 1, 1, 1, nil))
+
+	i.options.register(newSpinOption("MultiPV", 1, 1, search.MaxMultiPV, func(lines int) {
+		i.searcher.SetMultiPV(lines)
+	}))
+
+	i.options.register(newCheckOption("UCI_ShowWDL", false, func(show bool) {
+		i.searcher.SetShowWDL(show)
+	}))
+
+	// castling moves are read and written as the king capturing its own rook in chess960
+	i.options.register(newCheckOption("UCI_Chess960", false, nil))
+
+	i.options.register(newCheckOption("UCI_LimitStrength", false, func(bool) {
+		i.updateSkill()
+	}))
+
+	i.options.register(newSpinOption("UCI_Elo", search.MinElo, search.MinElo, search.MaxElo, func(int) {
+		i.updateSkill()
+	}))
+
+	i.options.register(newSpinOption("Skill Level", search.MaxSkillLevel, search.MinSkillLevel, search.MaxSkillLevel, func(int) {
+		i.updateSkill()
+	}))
+
+	overhead := int(search.DefaultMoveOverhead.Milliseconds())
+	i.options.register(newSpinOption("Move Overhead", overhead, 0, 5000, func(overhead int) {
+		i.searcher.SetMoveOverhead(time.Duration(overhead) * time.Millisecond)
+	}))
+
+	i.options.register(newStringOption("Debug Log File", "", func(path string) {
+		err := i.SetLogFile(path)
+		if err != nil {
+			fmt.Fprintln(i.output, "info string", err)
+		}
+	}))
+}
+
+// SetLogFile starts writing a transcript of every line received and sent to
+// the file at the given path. An empty path stops writing the transcript.
+func (i *uciInterface) SetLogFile(path string) error {
+	return i.transcript.open(path)
+}
+
+// debugf sends an info string with the formatted message when in debug mode.
+func (i *uciInterface) debugf(format string, args ...any) {
+	if i.debug {
+		fmt.Fprintf(i.output, "info string "+format+"\n", args...)
+	}
+}
+
+// updateSkill sets the strength of the search from the strength options.
+//
+// UCI_LimitStrength with UCI_Elo takes priority over Skill Level.
+func (i *uciInterface) updateSkill() {
+	limitStrength, _ := i.options.get("UCI_LimitStrength")
+	if limitStrength.Bool() {
+		elo, _ := i.options.get("UCI_Elo")
+		i.searcher.SetSkill(search.SkillFromElo(elo.Int()))
+		return
+	}
+
+	level, _ := i.options.get("Skill Level")
+	i.searcher.SetSkill(search.NewSkill(level.Int()))
+}
+
+// parsePosition creates the position described by the arguments of the
+// position command along with the hashes of the positions that came before
+// it.
+//
+// When chess960 is set the position is played as a chess960 game, positions
+// that can only come from chess960 are treated as chess960 either way.
+//
+// If one of the moves is invalid the position up to that move is returned
+// along with an error describing the invalid move.
+func parsePosition(args []string, chess960 bool) (chess.Position, []uint64, error) {
+	startingPosition, _ := chess.NewPosition(chess.StartingFen)
+	if len(args) < 1 {
+		return startingPosition, []uint64{}, errors.New("position requires either startpos or fen")
+	}
+
+	setup := args
+	moves := []string{}
+
+	movesIndex := slices.Index(args, "moves")
+	if movesIndex != -1 {
+		setup = args[:movesIndex]
+		moves = args[movesIndex+1:]
+	}
+
+	var fen string
+	switch setup[0] {
+	case "startpos":
+		fen = chess.StartingFen
+		break
+	case "fen":
+		fenParts := slices.Clone(setup[1:])
+
+		// some guis leave off the move counters, fill them in with their defaults
+		if len(fenParts) == 4 {
+			fenParts = append(fenParts, "0", "1")
+		}
+
+		fen = strings.Join(fenParts, " ")
+		break
+	default:
+		return startingPosition, []uint64{}, fmt.Errorf("unknown position type: %s", setup[0])
+	}
+
+	position, err := chess.NewPosition(fen)
+	if err != nil {
+		return startingPosition, []uint64{}, err
+	}
+
+	if chess960 {
+		position.SetChess960(true)
+	}
+
+	history := make([]uint64, 0, len(moves))
+	for _, uci := range moves {
+		move, ok := findUciMove(position, uci)
+		if !ok {
+			return position, history, fmt.Errorf("illegal move: %s", uci)
+		}
+
+		history = append(history, position.Hash())
+		position.MakeMove(move)
+	}
+
+	return position, history, nil
+}
+
+// findUciMove finds the legal move in the position matching the given uci move.
+func findUciMove(position chess.Position, uci string) (chess.Move, bool) {
+	moves := position.GenerateMoves(chess.LegalMoveGeneration)
+	for _, move := range moves {
+		if move.Uci(position.IsChess960()) == uci {
+			return move, true
+		}
+	}
+
+	return chess.NullMove, false
+}
+
+// goParameters are the names of the arguments the go command accepts.
+var goParameters = []string{
+	"searchmoves", "ponder", "wtime", "btime", "winc", "binc", "movestogo",
+	"depth", "nodes", "mate", "movetime", "infinite",
+}
+
+// parseSearchMoves parses the moves following searchmoves up to the next go
+// parameter, returning the moves and the number of arguments used.
+func parseSearchMoves(args []string, position chess.Position) ([]chess.Move, int, error) {
+	moves := []chess.Move{}
+
+	count := 0
+	for ; count < len(args) && !slices.Contains(goParameters, args[count]); count++ {
+		move, ok := findUciMove(position, args[count])
+		if !ok {
+			return moves, count, fmt.Errorf("illegal search move: %s", args[count])
+		}
+
+		moves = append(moves, move)
+	}
+
+	if len(moves) == 0 {
+		return moves, count, errors.New("searchmoves requires at least one move")
+	}
+
+	return moves, count, nil
+}
+
+// parseGoCommand creates the search limits from the arguments of the go command.
+//
+// If no limits other than mate are given, or the only clock given is the
+// opponent's, the search is limited to DefaultDepth.
+func parseGoCommand(args []string, position chess.Position) (search.SearchLimits, error) {
+	limits := search.SearchLimits{}
+	limited := false
+
+	for index := 0; index < len(args); index++ {
+		name := args[index]
+
+		if name == "infinite" {
+			limits.Infinite = true
+			limited = true
+			continue
+		}
+
+		if name == "ponder" {
+			limits.Ponder = true
+			continue
+		}
+
+		if name == "searchmoves" {
+			moves, count, err := parseSearchMoves(args[index+1:], position)
+			if err != nil {
+				return limits, err
+			}
+
+			limits.SearchMoves = moves
+			index += count
+			continue
+		}
+
+		if index+1 >= len(args) {
+			return limits, fmt.Errorf("missing value for %s", name)
+		}
+
+		index++
+		value, err := strconv.Atoi(args[index])
+		if err != nil {
+			return limits, fmt.Errorf("invalid value '%s' for %s", args[index], name)
+		}
+
+		switch name {
+		case "wtime":
+			limits.WhiteTime = time.Duration(value) * time.Millisecond
+			continue // the clock is checked once all of it has been parsed
+		case "btime":
+			limits.BlackTime = time.Duration(value) * time.Millisecond
+			continue
+		case "winc":
+			limits.WhiteIncrement = time.Duration(value) * time.Millisecond
+			continue
+		case "binc":
+			limits.BlackIncrement = time.Duration(value) * time.Millisecond
+			continue
+		case "movestogo":
+			limits.MovesToGo = value
+			continue
+		case "depth":
+			limits.Depth = value
+			break
+		case "nodes":
+			limits.Nodes = value
+			break
+		case "movetime":
+			limits.MoveTime = time.Duration(value) * time.Millisecond
+			break
+		case "mate":
+			limits.Mate = value
+			continue // the normal search after failing to find a mate still needs limits
+		default:
+			return limits, fmt.Errorf("unknown go parameter: %s", name)
+		}
+
+		limited = true
+	}
+
+	// the clock only limits the search if the side to move has time on it
+	remaining := limits.WhiteTime
+	if position.Turn() == chess.Black {
+		remaining = limits.BlackTime
+	}
+
+	if remaining > 0 {
+		limited = true
+	}
+
+	if !limited {
+		limits.Depth = DefaultDepth
+	}
+
+	return limits, nil
+}
+
+// startSearch searches the position in the background, writing the best move
+// once the search finishes.
+func (i *uciInterface) startSearch(position chess.Position, history []uint64, limits search.SearchLimits) {
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan struct{})
+	done := make(chan struct{})
+
+	i.cancel = cancel
+	i.finished = finished
+	i.done = done
+
+	i.searcher.SetHistory(history)
+
+	go func() {
+		defer close(done)
+		defer cancel()
+
+		bestMove := i.searcher.Search(ctx, position, limits, true)
+		close(finished)
+
+		chess960 := position.IsChess960()
+
+		ponderMove := i.searcher.PonderMove()
+		if ponderMove != chess.NullMove {
+			fmt.Fprintln(i.output, "bestmove", bestMove.Uci(chess960), "ponder", ponderMove.Uci(chess960))
+		} else {
+			fmt.Fprintln(i.output, "bestmove", bestMove.Uci(chess960))
+		}
+	}()
+}
+
+// searching returns whether a search is still looking for its best move.
+func (i *uciInterface) searching() bool {
+	if i.finished == nil {
+		return false
+	}
+
+	select {
+	case <-i.finished:
+		return false
+	default:
+		return true
 	}
 }
 
-func (i uciInterface) Loop() {
-	scanner := bufio.NewScanner(os.Stdin)
+// stopSearch stops the running search, if there is one, and waits for it to
+// write its best move.
+func (i *uciInterface) stopSearch() {
+	if i.done == nil {
+		return
+	}
+
+	i.cancel()
+	<-i.done
+
+	i.cancel = nil
+	i.finished = nil
+	i.done = nil
+}
+
+func (i *uciInterface) Loop() {
+	scanner := bufio.NewScanner(i.input)
 
 	position, _ := chess.NewPosition(chess.StartingFen)
+	history := []uint64{}
+
+	defer i.transcript.close()
+
+	// the search has to finish writing its best move before the engine exits
+	defer i.stopSearch()
 
-loop:
-	for {
-		scanner.Scan()
-		cmd, args := utils.ParseCommand(scanner.Text())
+	for scanner.Scan() {
+		line := scanner.Text()
+		i.transcript.record(transcriptInput, line)
+
+		cmd, args := utils.ParseCommand(line)
 
 		switch cmd {
+		case "":
+			break
+		case "debug":
+			if len(args) != 1 || (args[0] != "on" && args[0] != "off") {
+				fmt.Fprintln(i.output, "info string debug requires either on or off")
+				break
+			}
+
+			i.debug = args[0] == "on"
+			i.debugf("debug mode enabled")
+			break
 		case "uci":
-			fmt.Println("id name rosaline")
-			fmt.Println("id author rosaline contributors")
-			fmt.Println("uciok")
+			fmt.Fprintln(i.output, "id name rosaline")
+			fmt.Fprintln(i.output, "id author rosaline contributors")
+			for _, option := range i.options.options {
+				fmt.Fprintln(i.output, option)
+			}
+			fmt.Fprintln(i.output, "uciok")
+			break
+		case "setoption":
+			if i.searching() {
+				fmt.Fprintln(i.output, "info string options can't be changed while searching")
+				break
+			}
+
+			// the search may have found its best move but not written it yet
+			i.stopSearch()
+
+			name, value, err := parseSetOption(args)
+			if err != nil {
+				fmt.Fprintln(i.output, "info string", err)
+				break
+			}
+
+			err = i.options.set(name, value)
+			if err != nil {
+				fmt.Fprintln(i.output, "info string", err)
+				break
+			}
+
+			i.debugf("option %s set to '%s'", name, value)
 			break
 		case "isready":
-			fmt.Println("readyok")
+			fmt.Fprintln(i.output, "readyok")
 			break
 		case "ucinewgame":
+			if i.searching() {
+				fmt.Fprintln(i.output, "info string a new game can't be started while searching")
+				break
+			}
+
+			i.stopSearch()
+
 			i.searcher.Reset()
 			position, _ = chess.NewPosition(chess.StartingFen)
+			history = []uint64{}
 			break
 		case "position":
-			lastMove := args[len(args)-1]
-			position.MakeUciMove(lastMove)
+			chess960, _ := i.options.get("UCI_Chess960")
+			p, h, err := parsePosition(args, chess960.Bool())
+			if err != nil {
+				fmt.Fprintln(i.output, "info string", err)
+			}
+
+			position = p
+			history = h
+
+			i.debugf("position set to %s", position.Fen())
 			break
 		case "go":
-			go func() {
-				bestMove := i.searcher.Search(position, DefaultDepth, true)
-				position.MakeMove(bestMove)
-				fmt.Println("bestmove", bestMove)
-			}()
+			if i.searching() {
+				fmt.Fprintln(i.output, "info string a search is already running")
+				break
+			}
+
+			i.stopSearch()
+
+			limits, err := parseGoCommand(args, position)
+			if err != nil {
+				// the gui waits for a best move, so search with the default limits instead
+				fmt.Fprintf(i.output, "info string %s, searching to depth %d\n", err, DefaultDepth)
+				limits = search.SearchLimits{Depth: DefaultDepth, Ponder: slices.Contains(args, "ponder")}
+			}
+
+			if limits.Depth > search.MaxDepth {
+				fmt.Fprintf(i.output, "info string depth %d is more than the maximum of %d, searching to depth %d\n", limits.Depth, search.MaxDepth, search.MaxDepth)
+			}
+
+			i.debugf("searching %s with limits %+v", position.Fen(), limits)
+			i.startSearch(position, history, limits)
+			break
+		case "ponderhit":
+			if !i.searching() {
+				fmt.Fprintln(i.output, "info string ponderhit received without a search running")
+				break
+			}
+
+			i.searcher.PonderHit()
 			break
 		case "stop":
-			i.searcher.Stop()
+			if !i.searching() {
+				i.debugf("stop received without a search running")
+			}
+
+			i.stopSearch()
 			break
 		case "quit":
-			i.searcher.Stop()
-			break loop
+			return
+		default:
+			fmt.Fprintln(i.output, "info string unknown command:", cmd)
+			break
 		}
 	}
 }
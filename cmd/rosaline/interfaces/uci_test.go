@@ -0,0 +1,361 @@
+package interfaces
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rosaline/internal/chess"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGoSearchMoves(t *testing.T) {
+	position, _ := chess.NewPosition(chess.StartingFen)
+
+	limits, err := parseGoCommand(strings.Fields("searchmoves e2e4 d2d4 depth 3"), position)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	if len(limits.SearchMoves) != 2 || limits.SearchMoves[0].String() != "e2e4" || limits.SearchMoves[1].String() != "d2d4" {
+		t.Fatalf("%s: expected search moves e2e4 d2d4 got %v", t.Name(), limits.SearchMoves)
+	}
+
+	if limits.Depth != 3 {
+		t.Fatalf("%s: expected depth 3 got %d", t.Name(), limits.Depth)
+	}
+
+	_, err = parseGoCommand(strings.Fields("searchmoves e2e5"), position)
+	if err == nil {
+		t.Fatalf("%s: expected an error for an illegal search move", t.Name())
+	}
+
+	_, err = parseGoCommand(strings.Fields("searchmoves infinite"), position)
+	if err == nil {
+		t.Fatalf("%s: expected an error for searchmoves without moves", t.Name())
+	}
+}
+
+func TestParseGoClock(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Fen   string
+		Args  string
+		Depth int
+	}{
+		{Name: "OwnClock", Fen: chess.StartingFen, Args: "wtime 1000 btime 1000", Depth: 0},
+		{Name: "OpponentClock", Fen: chess.StartingFen, Args: "btime 1000 binc 100", Depth: DefaultDepth},
+		{Name: "BlackClock", Fen: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", Args: "btime 1000", Depth: 0},
+		{Name: "MovesToGo", Fen: chess.StartingFen, Args: "movestogo 10", Depth: DefaultDepth},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			position, _ := chess.NewPosition(c.Fen)
+
+			limits, err := parseGoCommand(strings.Fields(c.Args), position)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+			}
+
+			if limits.Depth != c.Depth {
+				t.Fatalf("%s: expected depth %d got %d", t.Name(), c.Depth, limits.Depth)
+			}
+		})
+	}
+}
+
+func TestParsePositionChess960(t *testing.T) {
+	// with UCI_Chess960 castling is written as the king capturing its own rook
+	position, _, err := parsePosition(strings.Fields("fen 4k3/8/8/8/8/8/8/1R2K1R1 w KQ - 0 1 moves e1g1"), true)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	expected := "4k3/8/8/8/8/8/8/1R3RK1 b - - 1 1"
+	if position.Fen() != expected {
+		t.Fatalf("%s: expected %s got %s", t.Name(), expected, position.Fen())
+	}
+
+	// without it the king moving two squares is castling
+	position, _, err = parsePosition(strings.Fields("startpos moves e2e4 e7e5 g1f3 b8c6 f1c4 g8f6 e1g1"), false)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	expected = "r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 5 4"
+	if position.Fen() != expected {
+		t.Fatalf("%s: expected %s got %s", t.Name(), expected, position.Fen())
+	}
+
+	_, _, err = parsePosition(strings.Fields("startpos moves e2e4 e7e5 g1f3 b8c6 f1c4 g8f6 e1g1"), true)
+	if err == nil {
+		t.Fatalf("%s: expected an error for castling written as a king move in chess960", t.Name())
+	}
+}
+
+func TestUciChess960(t *testing.T) {
+	session := newUciSession(t)
+
+	session.send("setoption name UCI_Chess960 value true")
+	session.send("position fen 4k3/8/8/8/8/8/8/6KR w K - 0 1")
+	session.send("go depth 1 searchmoves g1h1")
+	session.expect("bestmove g1h1")
+}
+
+// uciSession drives a uciInterface over pipes the same way a gui would.
+type uciSession struct {
+	t      *testing.T
+	input  *io.PipeWriter
+	output chan string
+	done   chan struct{}
+}
+
+func newUciSession(t *testing.T) *uciSession {
+	inputReader, inputWriter := io.Pipe()
+	outputReader, outputWriter := io.Pipe()
+
+	session := &uciSession{
+		t:      t,
+		input:  inputWriter,
+		output: make(chan string, 1024),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		newUciInterface(inputReader, outputWriter).Loop()
+		outputWriter.Close()
+		close(session.done)
+	}()
+
+	go func() {
+		scanner := bufio.NewScanner(outputReader)
+		for scanner.Scan() {
+			session.output <- scanner.Text()
+		}
+		close(session.output)
+	}()
+
+	t.Cleanup(func() {
+		inputWriter.Close()
+		<-session.done
+	})
+
+	return session
+}
+
+// send writes a command to the engine.
+func (s *uciSession) send(command string) {
+	_, err := fmt.Fprintln(s.input, command)
+	if err != nil {
+		s.t.Fatalf("%s: failed to send %s: %v", s.t.Name(), command, err)
+	}
+}
+
+// expect reads the output of the engine until a line starting with prefix is
+// found. Any best move that is written before then fails the test.
+func (s *uciSession) expect(prefix string) string {
+	timeout := time.After(30 * time.Second)
+
+	for {
+		select {
+		case line, ok := <-s.output:
+			if !ok {
+				s.t.Fatalf("%s: output closed while expecting %s", s.t.Name(), prefix)
+			}
+
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+
+			if strings.HasPrefix(line, "bestmove") {
+				s.t.Fatalf("%s: unexpected %s while expecting %s", s.t.Name(), line, prefix)
+			}
+			break
+		case <-timeout:
+			s.t.Fatalf("%s: timed out expecting %s", s.t.Name(), prefix)
+		}
+	}
+}
+
+// expectExit waits for the engine to exit, failing the test if it writes
+// another best move first.
+func (s *uciSession) expectExit() {
+	timeout := time.After(30 * time.Second)
+
+	for {
+		select {
+		case line, ok := <-s.output:
+			if !ok {
+				return
+			}
+
+			if strings.HasPrefix(line, "bestmove") {
+				s.t.Fatalf("%s: unexpected %s after quitting", s.t.Name(), line)
+			}
+			break
+		case <-timeout:
+			s.t.Fatalf("%s: timed out waiting for the engine to exit", s.t.Name())
+		}
+	}
+}
+
+func TestUciIsReadyDuringSearch(t *testing.T) {
+	session := newUciSession(t)
+
+	session.send("position startpos moves e2e4")
+	session.send("go infinite")
+	session.send("isready")
+	session.expect("readyok")
+
+	session.send("stop")
+	session.expect("bestmove")
+
+	session.send("isready")
+	session.expect("readyok")
+}
+
+func TestUciGoWhileSearching(t *testing.T) {
+	session := newUciSession(t)
+
+	session.send("go infinite")
+	session.send("go depth 1")
+	session.expect("info string a search is already running")
+
+	session.send("setoption name Hash value 1")
+	session.expect("info string options can't be changed while searching")
+
+	session.send("stop")
+	session.expect("bestmove")
+
+	session.send("stop")
+	session.send("isready")
+	session.expect("readyok")
+}
+
+func TestUciSearchFinishes(t *testing.T) {
+	session := newUciSession(t)
+
+	session.send("go depth 1")
+	session.expect("bestmove")
+
+	session.send("go depth 1")
+	session.expect("bestmove")
+
+	session.send("stop")
+	session.send("isready")
+	session.expect("readyok")
+}
+
+func TestUciGoError(t *testing.T) {
+	session := newUciSession(t)
+
+	// a go command that can't be parsed still searches so the gui gets a best move
+	session.send("go depth")
+	session.expect("info string missing value for depth, searching to depth 4")
+	session.expect("bestmove")
+
+	session.send("go depth 40 movetime 100")
+	session.expect("info string depth 40 is more than the maximum of 16, searching to depth 16")
+	session.expect("bestmove")
+}
+
+func TestUciPonderHit(t *testing.T) {
+	session := newUciSession(t)
+
+	session.send("go ponder movetime 100")
+	session.send("ponderhit")
+	session.expect("bestmove")
+
+	session.send("isready")
+	session.expect("readyok")
+}
+
+func TestUciQuitDuringSearch(t *testing.T) {
+	session := newUciSession(t)
+
+	session.send("go infinite")
+	session.send("isready")
+	session.expect("readyok")
+
+	session.send("quit")
+	session.expect("bestmove")
+	session.expectExit()
+}
+
+func TestUciEndOfInput(t *testing.T) {
+	session := newUciSession(t)
+
+	session.send("go infinite")
+	session.input.Close()
+
+	session.expect("bestmove")
+	session.expectExit()
+}
+
+func TestUciDiagnostics(t *testing.T) {
+	session := newUciSession(t)
+
+	session.send("foo bar")
+	session.expect("info string unknown command: foo")
+
+	session.send("ponderhit")
+	session.expect("info string ponderhit received without a search running")
+
+	session.send("debug on")
+	session.expect("info string debug mode enabled")
+
+	session.send("position startpos moves e2e4")
+	session.expect("info string position set to " + "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1")
+
+	session.send("debug off")
+	session.send("position startpos")
+	session.send("isready")
+	line := session.expect("")
+	if line != "readyok" {
+		t.Fatalf("%s: expected no diagnostics after debug off got %s", t.Name(), line)
+	}
+}
+
+func TestUciTranscript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.log")
+
+	session := newUciSession(t)
+	session.send("setoption name Debug Log File value " + path)
+	session.send("isready")
+	session.expect("readyok")
+	session.send("quit")
+	session.expectExit()
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s: failed to read the transcript: %v", t.Name(), err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	expected := []string{">> isready", "<< readyok", ">> quit"}
+	if len(lines) != len(expected) {
+		t.Fatalf("%s: expected %d lines got %d: %q", t.Name(), len(expected), len(lines), lines)
+	}
+
+	for index, line := range lines {
+		if len(line) <= len(transcriptTimeFormat) {
+			t.Fatalf("%s: line %d is missing a timestamp: %s", t.Name(), index+1, line)
+		}
+
+		timestamp := line[:len(transcriptTimeFormat)]
+		entry := line[len(transcriptTimeFormat)+1:]
+
+		if entry != expected[index] {
+			t.Fatalf("%s: expected line %d to be %s got %s", t.Name(), index+1, expected[index], line)
+		}
+
+		_, err := time.Parse(transcriptTimeFormat, timestamp)
+		if err != nil {
+			t.Fatalf("%s: invalid timestamp on line %d: %v", t.Name(), index+1, err)
+		}
+	}
+}
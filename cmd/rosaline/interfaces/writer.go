@@ -0,0 +1,34 @@
+package interfaces
+
+import (
+	"io"
+	"sync"
+)
+
+// lineWriter is a writer that can be used from multiple goroutines at once.
+//
+// Each write is done as a whole so lines written by one goroutine are never
+// mixed up with lines written by another. Everything written is also
+// recorded in the transcript.
+type lineWriter struct {
+	mutex      sync.Mutex
+	writer     io.Writer
+	transcript *transcript
+}
+
+// newLineWriter creates a lineWriter which writes to the given writer.
+func newLineWriter(writer io.Writer, transcript *transcript) *lineWriter {
+	return &lineWriter{
+		writer:     writer,
+		transcript: transcript,
+	}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.transcript.record(transcriptOutput, string(p))
+
+	return w.writer.Write(p)
+}
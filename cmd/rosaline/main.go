@@ -10,6 +10,7 @@ import (
 
 type config struct {
 	Mode string `clap:"--mode,-m"`
+	Log  string `clap:"--log,-l"`
 }
 
 func main() {
@@ -25,11 +26,22 @@ func main() {
 		return
 	}
 
+	if cfg.Log != "" && cfg.Mode != "uci" {
+		fmt.Println("a log file can only be written in uci mode")
+		return
+	}
+
 	var engineInterface interfaces.EngineInterface
 	if cfg.Mode == "cli" {
 		engineInterface = interfaces.NewCliProtocolHandler()
 	} else {
-		engineInterface = interfaces.NewUciProtocolHandler()
+		uci := interfaces.NewUciProtocolHandler()
+		if err := uci.SetLogFile(cfg.Log); err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		engineInterface = uci
 	}
 
 	engineInterface.Loop()
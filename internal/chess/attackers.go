@@ -1,136 +1,81 @@
 package chess
 
-func generatePawnAttacks(position Position, color Color) []Move {
-	attacks := []Move{}
-	direction := pawnDirection(color)
+// pawnAttacks are the squares a pawn on each square attacks, indexed by the
+// color of the pawn.
+var pawnAttacks [numSides][64]BitBoard
 
-	pawnBB := position.pawnBB & position.GetColorBB(color)
-	for pawnBB > 0 {
-		square := Square(pawnBB.PopLsb())
+func init() {
+	for _, color := range []Color{White, Black} {
+		direction := Square(pawnDirection(color))
 
-		if square.File() != 1 {
-			move := NewMove(square, square+Square(direction)+Square(west), CaptureMove)
-			attacks = append(attacks, move)
-		}
-
-		if square.File() != 8 {
-			move := NewMove(square, square+Square(direction)+Square(east), CaptureMove)
-			attacks = append(attacks, move)
-		}
-	}
-
-	return attacks
-}
-
-func generateKnightAttacks(position Position, color Color) []Move {
-	attacks := []Move{}
-
-	knightBB := position.knightBB & position.GetColorBB(color)
+		for square := A1; square <= H8; square++ {
+			attacks := BitBoard(0)
 
-	for knightBB > 0 {
-		square := Square(knightBB.PopLsb())
-		moveBB := knightMoves[square]
+			forward := square + direction
+			if forward.IsValid() {
+				if square.File() != 1 {
+					attacks.SetBit(uint64(forward + Square(west)))
+				}
 
-		for moveBB > 0 {
-			toSquare := Square(moveBB.PopLsb())
-			move := NewMove(square, toSquare, CaptureMove)
-			attacks = append(attacks, move)
-		}
-	}
-
-	return attacks
-}
+				if square.File() != 8 {
+					attacks.SetBit(uint64(forward + Square(east)))
+				}
+			}
 
-func generateBishopAttacks(pieceBB BitBoard, occupied BitBoard) []Move {
-	attacks := []Move{}
-
-	for pieceBB > 0 {
-		fromSquare := Square(pieceBB.PopLsb())
-
-		attackBB := getBishopAttacks(occupied, fromSquare)
-		for attackBB > 0 {
-			toSquare := Square(attackBB.PopLsb())
-			move := NewMove(fromSquare, toSquare, CaptureMove)
-			attacks = append(attacks, move)
+			pawnAttacks[sideIndex(color)][square] = attacks
 		}
 	}
-
-	return attacks
 }
 
-func generateRookAttacks(pieceBB BitBoard, occupied BitBoard) []Move {
-	attacks := []Move{}
-
-	for pieceBB > 0 {
-		fromSquare := Square(pieceBB.PopLsb())
-
-		attackBB := getRookAttacks(occupied, fromSquare)
-		for attackBB > 0 {
-			toSquare := Square(attackBB.PopLsb())
-			move := NewMove(fromSquare, toSquare, CaptureMove)
-			attacks = append(attacks, move)
-		}
+// sideIndex returns the index used for the color in tables that have an
+// entry for each side.
+func sideIndex(color Color) int {
+	if color == Black {
+		return 1
 	}
 
-	return attacks
+	return 0
 }
 
-func generateQueenAttacks(position Position, occupied BitBoard, color Color) []Move {
-	attacks := []Move{}
-
-	queenBB := position.queenBB & position.GetColorBB(color)
-
-	rookAttacks := generateRookAttacks(queenBB, occupied)
-	attacks = append(attacks, rookAttacks...)
-	bishopAttacks := generateBishopAttacks(queenBB, occupied)
-	attacks = append(attacks, bishopAttacks...)
-
-	return attacks
+// attackersOf returns every piece of either color attacking the square when
+// the given squares are occupied.
+//
+// Attacks are symmetric so rather than generating the attacks of every piece,
+// the attacks of each piece type are generated from the square itself and
+// checked for pieces of that type.
+func (p Position) attackersOf(square Square, occupied BitBoard) BitBoard {
+	// a white pawn attacks the square if a black pawn on the square would attack the white pawn
+	pawns := (pawnAttacks[sideIndex(Black)][square] & p.whiteBB) | (pawnAttacks[sideIndex(White)][square] & p.blackBB)
+	attackers := pawns & p.pawnBB
+
+	attackers |= knightMoves[square] & p.knightBB
+	attackers |= kingMoves[square] & p.kingBB
+	attackers |= getBishopAttacks(occupied, square) & (p.bishopBB | p.queenBB)
+	attackers |= getRookAttacks(occupied, square) & (p.rookBB | p.queenBB)
+
+	return attackers
 }
 
-func generateKingAttacks(position Position, color Color) []Move {
-	attacks := []Move{}
+// attackersOfColor returns the pieces of the given color attacking the
+// square when the given squares are occupied.
+func (p Position) attackersOfColor(square Square, color Color, occupied BitBoard) BitBoard {
+	colorBB := p.GetColorBB(color)
 
-	kingBB := position.kingBB & position.GetColorBB(color)
-	for kingBB > 0 {
-		fromSquare := Square(kingBB.PopLsb())
+	attackers := pawnAttacks[sideIndex(color.OpposingSide())][square] & p.pawnBB
+	attackers |= knightMoves[square] & p.knightBB
+	attackers |= kingMoves[square] & p.kingBB
+	attackers &= colorBB
 
-		moveBB := kingMoves[fromSquare]
-		for moveBB > 0 {
-			toSquare := Square(moveBB.PopLsb())
-			move := NewMove(fromSquare, toSquare, CaptureMove)
-			attacks = append(attacks, move)
-		}
+	// the sliding pieces are only looked for if there are any left
+	bishops := (p.bishopBB | p.queenBB) & colorBB
+	if bishops != 0 {
+		attackers |= getBishopAttacks(occupied, square) & bishops
 	}
 
-	return attacks
-}
-
-func getAttackers(position Position, color Color) []Move {
-	attacks := []Move{}
-
-	occupied := position.whiteBB | position.blackBB
-	colorBB := position.GetColorBB(color)
-
-	pawnAttacks := generatePawnAttacks(position, color)
-	attacks = append(attacks, pawnAttacks...)
-
-	knightAttacks := generateKnightAttacks(position, color)
-	attacks = append(attacks, knightAttacks...)
-
-	bishopBB := position.bishopBB & colorBB
-	bishopAttacks := generateBishopAttacks(bishopBB, occupied)
-	attacks = append(attacks, bishopAttacks...)
-
-	rookBB := position.rookBB & colorBB
-	rookAttacks := generateRookAttacks(rookBB, occupied)
-	attacks = append(attacks, rookAttacks...)
-
-	queenAttacks := generateQueenAttacks(position, occupied, color)
-	attacks = append(attacks, queenAttacks...)
-
-	kingAttacks := generateKingAttacks(position, color)
-	attacks = append(attacks, kingAttacks...)
+	rooks := (p.rookBB | p.queenBB) & colorBB
+	if rooks != 0 {
+		attackers |= getRookAttacks(occupied, square) & rooks
+	}
 
-	return attacks
+	return attackers
 }
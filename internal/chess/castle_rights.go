@@ -1,6 +1,9 @@
 package chess
 
-import "strings"
+import (
+	"math/bits"
+	"strings"
+)
 
 type CastlingRights uint8
 
@@ -35,3 +38,63 @@ func (rights CastlingRights) String() string {
 
 	return builder.String()
 }
+
+// allCastlingRights are each of the individual castling rights.
+var allCastlingRights = [4]CastlingRights{WhiteCastleKingside, WhiteCastleQueenside, BlackCastleKingside, BlackCastleQueenside}
+
+// defaultCastlingRooks are the squares the rooks castle from in standard chess,
+// indexed by CastlingRights.index.
+var defaultCastlingRooks = [4]Square{H8, A8, H1, A1}
+
+// castlingRight returns the castling right for the given color and side of the board.
+func castlingRight(color Color, kingside bool) CastlingRights {
+	if color == White {
+		if kingside {
+			return WhiteCastleKingside
+		}
+
+		return WhiteCastleQueenside
+	}
+
+	if kingside {
+		return BlackCastleKingside
+	}
+
+	return BlackCastleQueenside
+}
+
+// index returns the index of a single castling right, used to look up
+// information stored for each castling right.
+func (rights CastlingRights) index() int {
+	return bits.TrailingZeros8(uint8(rights))
+}
+
+// color returns the color a single castling right belongs to.
+func (rights CastlingRights) color() Color {
+	if rights&WhiteCastleBoth > 0 {
+		return White
+	}
+
+	return Black
+}
+
+// kingside returns whether a single castling right is for castling kingside.
+func (rights CastlingRights) kingside() bool {
+	return rights&(WhiteCastleKingside|BlackCastleKingside) > 0
+}
+
+// castlingDestinations returns the squares the king and rook end up on after
+// castling with a single castling right. These are the same in Chess960 and
+// standard chess.
+func castlingDestinations(right CastlingRights) (Square, Square) {
+	rank := 1
+	if right.color() == Black {
+		rank = 8
+	}
+
+	if right.kingside() {
+		return SquareFromRankFile(rank, 7), SquareFromRankFile(rank, 6)
+	}
+
+	return SquareFromRankFile(rank, 3), SquareFromRankFile(rank, 4)
+}
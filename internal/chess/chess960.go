@@ -0,0 +1,73 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	Chess960Positions     = 960 // The number of Chess960 starting positions.
+	StandardChess960Index = 518 // The index of the standard chess starting position.
+)
+
+// chess960KnightPlacements are the ways the knights, rooks and king can be
+// placed on the five files left after placing the bishops and queen.
+var chess960KnightPlacements = [10]string{
+	"NNRKR", "NRNKR", "NRKNR", "NRKRN", "RNNKR",
+	"RNKNR", "RNKRN", "RKNNR", "RKNRN", "RKRNN",
+}
+
+// Chess960Fen returns the FEN of the Chess960 starting position with the given
+// index between 0 and 959, using the standard numbering scheme where 518 is
+// the standard chess starting position.
+func Chess960Fen(index int) (string, error) {
+	if index < 0 || index >= Chess960Positions {
+		return "", fmt.Errorf("%w: chess960 index %d is not between 0 and %d", ErrInvalidPosition, index, Chess960Positions-1)
+	}
+
+	pieces := [8]rune{}
+
+	// the light squared bishop goes on the b, d, f or h file
+	pieces[(index%4)*2+1] = 'B'
+	index /= 4
+
+	// the dark squared bishop goes on the a, c, e or g file
+	pieces[(index%4)*2] = 'B'
+	index /= 4
+
+	queen := index % 6
+	index /= 6
+
+	// the queen goes on one of the six empty files then the knights, rooks and king fill the rest
+	knights := chess960KnightPlacements[index]
+	remaining := knights[:queen] + "Q" + knights[queen:]
+	for file := range pieces {
+		if pieces[file] == 0 {
+			pieces[file] = rune(remaining[0])
+			remaining = remaining[1:]
+		}
+	}
+
+	backRank := string(pieces[:])
+	fen := fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w KQkq - 0 1", strings.ToLower(backRank), backRank)
+
+	return fen, nil
+}
+
+// NewChess960Position creates the Chess960 starting position with the given
+// index between 0 and 959.
+func NewChess960Position(index int) (Position, error) {
+	fen, err := Chess960Fen(index)
+	if err != nil {
+		return Position{}, err
+	}
+
+	position, err := NewPosition(fen)
+	if err != nil {
+		return Position{}, err
+	}
+
+	position.SetChess960(true)
+
+	return position, nil
+}
@@ -0,0 +1,67 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+func chess960FenTest(t *testing.T, index int, expectedFen string) {
+	fen, err := Chess960Fen(index)
+	if err != nil {
+		t.Fatalf("%s: index %d returned error: %s", t.Name(), index, err)
+	}
+
+	if fen != expectedFen {
+		t.Fatalf("%s: expected %s for index %d got %s", t.Name(), expectedFen, index, fen)
+	}
+}
+
+func TestChess960Fen(t *testing.T) {
+	chess960FenTest(t, 0, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1")
+	chess960FenTest(t, StandardChess960Index, StartingFen)
+	chess960FenTest(t, 959, "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w KQkq - 0 1")
+
+	for _, index := range []int{-1, Chess960Positions} {
+		_, err := Chess960Fen(index)
+		if !errors.Is(err, ErrInvalidPosition) {
+			t.Fatalf("%s: expected an invalid position error for index %d got %v", t.Name(), index, err)
+		}
+	}
+}
+
+func TestChess960Positions(t *testing.T) {
+	fens := map[string]bool{}
+
+	for index := 0; index < Chess960Positions; index++ {
+		position, err := NewChess960Position(index)
+		if err != nil {
+			t.Fatalf("%s: index %d returned error: %s", t.Name(), index, err)
+		}
+
+		if !position.IsChess960() {
+			t.Fatalf("%s: expected position %d to be chess960", t.Name(), index)
+		}
+
+		fens[position.Fen()] = true
+
+		// the king has to be between the rooks
+		king := position.GetKingSquare(White)
+		kingside := position.CastlingRook(WhiteCastleKingside)
+		queenside := position.CastlingRook(WhiteCastleQueenside)
+		if !position.IsPieceAt(kingside, Rook, White) || !position.IsPieceAt(queenside, Rook, White) || queenside > king || king > kingside {
+			t.Fatalf("%s: king is not between the rooks in position %d: %s", t.Name(), index, position.Fen())
+		}
+
+		// the bishops have to be on opposite colored squares
+		bishops := position.GetPieceBB(Bishop) & position.GetColorBB(White)
+		first := Square(bishops.PopLsb())
+		second := Square(bishops.PopLsb())
+		if (first.File()+second.File())%2 == 0 {
+			t.Fatalf("%s: bishops are on the same color in position %d: %s", t.Name(), index, position.Fen())
+		}
+	}
+
+	if len(fens) != Chess960Positions {
+		t.Fatalf("%s: expected %d different positions got %d", t.Name(), Chess960Positions, len(fens))
+	}
+}
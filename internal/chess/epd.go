@@ -0,0 +1,324 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Operation is a single opcode of an EPD along with its operands, such as
+// bm Nf3 or id "WAC.001".
+type Operation struct {
+	Opcode   string
+	Operands []string
+}
+
+// EPD is a position along with operations describing it, as used by test
+// suites to record the best move, its evaluation or perft counts.
+type EPD struct {
+	Position   Position
+	Operations []Operation
+}
+
+// NewEPD parses an EPD line made up of the first four fields of a FEN
+// followed by operations each ending with a semicolon.
+//
+// The move counters are taken from the hmvc and fmvn operations if there
+// are any, perft suites that put the counters after the four fields are
+// also accepted.
+func NewEPD(line string) (EPD, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return EPD{}, fmt.Errorf("%w: expected at least 4 fields got %d", ErrInvalidEpd, len(fields))
+	}
+
+	rest := line
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		rest = rest[len(fields[i]):]
+	}
+
+	halfMoves := "0"
+	fullMoves := "1"
+
+	// some perft suites include the move counters after the four fields
+	if len(fields) >= 6 && isNumber(fields[4]) && isNumber(fields[5]) {
+		halfMoves = fields[4]
+		fullMoves = fields[5]
+
+		for i := 4; i < 6; i++ {
+			rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+			rest = rest[len(fields[i]):]
+		}
+	}
+
+	operations, err := parseOperations(rest)
+	if err != nil {
+		return EPD{}, err
+	}
+
+	epd := EPD{Operations: operations}
+
+	if operands, ok := epd.Operation("hmvc"); ok && len(operands) == 1 {
+		halfMoves = operands[0]
+	}
+
+	if operands, ok := epd.Operation("fmvn"); ok && len(operands) == 1 {
+		fullMoves = operands[0]
+	}
+
+	fen := strings.Join(append(fields[:4:4], halfMoves, fullMoves), " ")
+	epd.Position, err = NewPosition(fen)
+	if err != nil {
+		return EPD{}, err
+	}
+
+	return epd, nil
+}
+
+// isNumber returns whether the text is made up only of digits.
+func isNumber(text string) bool {
+	_, err := strconv.Atoi(text)
+	return err == nil
+}
+
+// parseOperations parses the operations of an EPD. Operands in quotes can
+// contain spaces and semicolons.
+func parseOperations(text string) ([]Operation, error) {
+	operations := []Operation{}
+
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	quoted := false
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for _, character := range text {
+		switch {
+		case quoted && character == '"':
+			quoted = false
+			endWord()
+			break
+		case quoted:
+			word.WriteRune(character)
+			break
+		case character == '"':
+			endWord()
+			quoted = true
+			inWord = true
+			break
+		case character == ';':
+			endWord()
+
+			if len(words) == 0 {
+				// perft suites start every operation with a semicolon which leaves empty operations
+				break
+			}
+
+			operations = append(operations, Operation{Opcode: words[0], Operands: words[1:]})
+			words = []string{}
+			break
+		case unicode.IsSpace(character):
+			endWord()
+			break
+		default:
+			word.WriteRune(character)
+			inWord = true
+			break
+		}
+	}
+
+	if quoted {
+		return operations, fmt.Errorf("%w: string operand is never closed", ErrInvalidEpd)
+	}
+
+	endWord()
+
+	// the last operation doesn't always end with a semicolon
+	if len(words) > 0 {
+		operations = append(operations, Operation{Opcode: words[0], Operands: words[1:]})
+	}
+
+	return operations, nil
+}
+
+// String returns the EPD with its operations in the order they were added.
+func (e EPD) String() string {
+	var builder strings.Builder
+
+	fields := strings.Fields(e.Position.Fen())
+	builder.WriteString(strings.Join(fields[:4], " "))
+
+	for _, operation := range e.Operations {
+		builder.WriteString(" ")
+		builder.WriteString(operation.Opcode)
+
+		for _, operand := range operation.Operands {
+			builder.WriteString(" ")
+			if isStringOpcode(operation.Opcode) || operand == "" || strings.ContainsAny(operand, " \t;\"") {
+				builder.WriteString(strconv.Quote(operand))
+			} else {
+				builder.WriteString(operand)
+			}
+		}
+
+		builder.WriteString(";")
+	}
+
+	return builder.String()
+}
+
+// isStringOpcode returns whether the operands of the opcode are always written as strings.
+func isStringOpcode(opcode string) bool {
+	if opcode == "id" {
+		return true
+	}
+
+	// c0 to c9 are comments
+	return len(opcode) == 2 && opcode[0] == 'c' && opcode[1] >= '0' && opcode[1] <= '9'
+}
+
+// Operation returns the operands of the operation with the given opcode.
+func (e EPD) Operation(opcode string) ([]string, bool) {
+	for _, operation := range e.Operations {
+		if operation.Opcode == opcode {
+			return operation.Operands, true
+		}
+	}
+
+	return nil, false
+}
+
+// SetOperation sets the operands of the operation with the given opcode,
+// adding the operation if the EPD doesn't have it yet.
+func (e *EPD) SetOperation(opcode string, operands ...string) {
+	for i, operation := range e.Operations {
+		if operation.Opcode == opcode {
+			e.Operations[i].Operands = operands
+			return
+		}
+	}
+
+	e.Operations = append(e.Operations, Operation{Opcode: opcode, Operands: operands})
+}
+
+// ID returns the id of the position in its test suite.
+func (e EPD) ID() string {
+	operands, _ := e.Operation("id")
+	return strings.Join(operands, " ")
+}
+
+// Comment returns the comment with the given number from 0 to 9.
+func (e EPD) Comment(number int) string {
+	operands, _ := e.Operation(fmt.Sprintf("c%d", number))
+	return strings.Join(operands, " ")
+}
+
+// BestMoves returns the moves of the bm operation, any of which solves the position.
+func (e EPD) BestMoves() ([]Move, error) {
+	return e.moves("bm")
+}
+
+// AvoidMoves returns the moves of the am operation, which should not be played.
+func (e EPD) AvoidMoves() ([]Move, error) {
+	return e.moves("am")
+}
+
+// moves parses the operands of the operation as alternative moves in the position.
+func (e EPD) moves(opcode string) ([]Move, error) {
+	operands, _ := e.Operation(opcode)
+
+	moves := make([]Move, 0, len(operands))
+	for _, operand := range operands {
+		move, err := e.Position.ParseSan(operand)
+		if err != nil {
+			return moves, err
+		}
+
+		moves = append(moves, move)
+	}
+
+	return moves, nil
+}
+
+// PrincipalVariation returns the moves of the pv operation, each played after the one before.
+func (e EPD) PrincipalVariation() ([]Move, error) {
+	operands, _ := e.Operation("pv")
+
+	position := e.Position
+	moves := make([]Move, 0, len(operands))
+	for _, operand := range operands {
+		move, err := position.ParseSan(operand)
+		if err != nil {
+			return moves, err
+		}
+
+		position.MakeMove(move)
+		moves = append(moves, move)
+	}
+
+	return moves, nil
+}
+
+// CentipawnEvaluation returns the evaluation of the ce operation in
+// centipawns from the perspective of the player to move.
+func (e EPD) CentipawnEvaluation() (int, bool) {
+	return e.intOperand("ce")
+}
+
+// AnalysisDepth returns the depth of the acd operation that the evaluation
+// and principal variation were found at.
+func (e EPD) AnalysisDepth() (int, bool) {
+	return e.intOperand("acd")
+}
+
+// PerftNodes returns the number of leaf nodes at the given depth from the
+// Dn operation used by perft suites.
+func (e EPD) PerftNodes(depth int) (uint64, bool) {
+	operands, ok := e.Operation(fmt.Sprintf("D%d", depth))
+	if !ok || len(operands) != 1 {
+		return 0, false
+	}
+
+	nodes, err := strconv.ParseUint(operands[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return nodes, true
+}
+
+// PerftDepths returns the depths that the EPD has perft counts for, in increasing order.
+func (e EPD) PerftDepths() []int {
+	depths := []int{}
+
+	for depth := 1; ; depth++ {
+		if _, ok := e.PerftNodes(depth); !ok {
+			return depths
+		}
+
+		depths = append(depths, depth)
+	}
+}
+
+func (e EPD) intOperand(opcode string) (int, bool) {
+	operands, ok := e.Operation(opcode)
+	if !ok || len(operands) != 1 {
+		return 0, false
+	}
+
+	value, err := strconv.Atoi(operands[0])
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
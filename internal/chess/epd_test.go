@@ -0,0 +1,129 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewEPD(t *testing.T) {
+	epd, err := NewEPD(`2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001"; c0 "mate; in 2"; ce 300; acd 12; pv Qg6 fxg6;`)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	if epd.Position.Fen() != "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1" {
+		t.Fatalf("%s: expected the position to get default move counters got %s", t.Name(), epd.Position.Fen())
+	}
+
+	if epd.ID() != "WAC.001" {
+		t.Fatalf("%s: expected id WAC.001 got %s", t.Name(), epd.ID())
+	}
+
+	if epd.Comment(0) != "mate; in 2" {
+		t.Fatalf("%s: expected the quoted comment to keep its semicolon got '%s'", t.Name(), epd.Comment(0))
+	}
+
+	bestMoves, err := epd.BestMoves()
+	if err != nil || len(bestMoves) != 1 || bestMoves[0].Uci(false) != "g3g6" {
+		t.Fatalf("%s: expected best move g3g6 got %v %v", t.Name(), bestMoves, err)
+	}
+
+	if ce, ok := epd.CentipawnEvaluation(); !ok || ce != 300 {
+		t.Fatalf("%s: expected ce 300 got %d", t.Name(), ce)
+	}
+
+	if acd, ok := epd.AnalysisDepth(); !ok || acd != 12 {
+		t.Fatalf("%s: expected acd 12 got %d", t.Name(), acd)
+	}
+
+	pv, err := epd.PrincipalVariation()
+	if err != nil || len(pv) != 2 || pv[1].Uci(false) != "f7g6" {
+		t.Fatalf("%s: expected pv g3g6 f7g6 got %v %v", t.Name(), pv, err)
+	}
+
+	expected := `2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001"; c0 "mate; in 2"; ce 300; acd 12; pv Qg6 fxg6;`
+	if epd.String() != expected {
+		t.Fatalf("%s: expected\n%s\ngot\n%s", t.Name(), expected, epd.String())
+	}
+}
+
+func TestNewEPDMoveCounters(t *testing.T) {
+	cases := []struct {
+		Name string
+		Epd  string
+		Fen  string
+	}{
+		{
+			Name: "Operations",
+			Epd:  "4k3/8/8/8/8/8/8/4K2R w K - hmvc 7; fmvn 42;",
+			Fen:  "4k3/8/8/8/8/8/8/4K2R w K - 7 42",
+		},
+		{
+			Name: "Fields",
+			Epd:  "4k3/8/8/8/8/8/8/4K2R w K - 3 12 ;D1 15 ;D2 66",
+			Fen:  "4k3/8/8/8/8/8/8/4K2R w K - 3 12",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			epd, err := NewEPD(c.Epd)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+			}
+
+			if epd.Position.Fen() != c.Fen {
+				t.Fatalf("%s: expected fen %s got %s", t.Name(), c.Fen, epd.Position.Fen())
+			}
+		})
+	}
+}
+
+func TestEPDPerftCounts(t *testing.T) {
+	epd, err := NewEPD("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - ;D1 20 ;D2 400 ;D3 8902")
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	depths := epd.PerftDepths()
+	if len(depths) != 3 {
+		t.Fatalf("%s: expected perft counts for 3 depths got %v", t.Name(), depths)
+	}
+
+	if nodes, ok := epd.PerftNodes(3); !ok || nodes != 8902 {
+		t.Fatalf("%s: expected 8902 nodes at depth 3 got %d", t.Name(), nodes)
+	}
+
+	if _, ok := epd.PerftNodes(4); ok {
+		t.Fatalf("%s: expected no perft count at depth 4", t.Name())
+	}
+
+	epd.SetOperation("D4", "197281")
+	epd.SetOperation("D1", "20")
+
+	expected := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - D1 20; D2 400; D3 8902; D4 197281;"
+	if epd.String() != expected {
+		t.Fatalf("%s: expected\n%s\ngot\n%s", t.Name(), expected, epd.String())
+	}
+}
+
+func TestNewEPDErrors(t *testing.T) {
+	cases := []struct {
+		Name string
+		Epd  string
+		Err  error
+	}{
+		{Name: "TooFewFields", Epd: "8/8/8/8 w -", Err: ErrInvalidEpd},
+		{Name: "UnclosedString", Epd: `4k3/8/8/8/8/8/8/4K3 w - - id "never closed;`, Err: ErrInvalidEpd},
+		{Name: "InvalidTurn", Epd: "4k3/8/8/8/8/8/8/4K3 x - - id \"x\";", Err: ErrInvalidFen},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			_, err := NewEPD(c.Epd)
+			if !errors.Is(err, c.Err) {
+				t.Fatalf("%s: expected error %v got %v", t.Name(), c.Err, err)
+			}
+		})
+	}
+}
@@ -7,3 +7,5 @@ import (
 var ErrInvalidPosition = errors.New("invalid postion")
 var ErrInvalidFen = errors.New("invalid fen")
 var ErrInvalidMove = errors.New("invalid move")
+var ErrAmbiguousMove = errors.New("ambiguous move")
+var ErrInvalidEpd = errors.New("invalid epd")
@@ -1,5 +1,7 @@
 package chess
 
+import "slices"
+
 type GameStatus uint8
 
 const (
@@ -12,11 +14,41 @@ const (
 	BlackResigned
 )
 
+// SevenTagRoster are the names of the tags every recorded game should have, in
+// the order they are normally written.
+var SevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// Tag is a named piece of information about a game, such as the players or
+// the event it was played at.
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Evaluation is an engine's evaluation of the position after a move.
+type Evaluation struct {
+	Score int  // The score in centipawns from white's perspective, or the number of moves until mate when Mate is set.
+	Mate  bool // Whether the score is a mate, a negative score means black is mating.
+}
+
+// GameMove is a move played in a game along with its annotations.
+type GameMove struct {
+	Move          Move         // The move that was played.
+	CommentBefore string       // A comment about the position before the move.
+	Comment       string       // A comment about the move.
+	Evaluation    *Evaluation  // The evaluation of the position after the move, if there is one.
+	Nags          []int        // Numeric annotation glyphs for the move, such as 1 for a good move.
+	Variations    [][]GameMove // Alternatives to the move, each played from the position before it.
+}
+
 // Game represents the current state of a chess game.
 type Game struct {
 	Position    Position   // The current position.
 	status      GameStatus // The status of the game.
 	drawOffered bool       // Whether a draw has been offered.
+	startingFen string     // The fen of the position the game started from.
+	tags        []Tag      // The tags of the game in the order they were added.
+	moves       []GameMove // The moves played in the game.
 }
 
 // NewGame returns a new chess game.
@@ -30,42 +62,107 @@ func NewGame(fen string) (Game, error) {
 		Position:    position,
 		status:      InProgress,
 		drawOffered: false,
+		startingFen: position.Fen(),
+		tags:        []Tag{},
+		moves:       []GameMove{},
 	}, nil
 }
 
+// StartingFen returns the fen of the position the game started from.
+func (g Game) StartingFen() string {
+	return g.startingFen
+}
+
+// Tags returns the tags of the game in the order they were added.
+func (g Game) Tags() []Tag {
+	return g.tags
+}
+
+// Tag returns the value of the tag with the given name.
+func (g Game) Tag(name string) (string, bool) {
+	for _, tag := range g.tags {
+		if tag.Name == name {
+			return tag.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// SetTag sets the value of the tag with the given name, adding the tag if
+// the game doesn't have it yet.
+func (g *Game) SetTag(name string, value string) {
+	for i, tag := range g.tags {
+		if tag.Name == name {
+			g.tags[i].Value = value
+			return
+		}
+	}
+
+	g.tags = append(g.tags, Tag{Name: name, Value: value})
+}
+
+// Moves returns the moves played in the game.
+func (g Game) Moves() []GameMove {
+	return g.moves
+}
+
+// LastMove returns the last move played in the game so that it can be
+// annotated, or nil if no moves have been played.
+func (g *Game) LastMove() *GameMove {
+	if len(g.moves) == 0 {
+		return nil
+	}
+
+	return &g.moves[len(g.moves)-1]
+}
+
 // MakeUciMove makes a move from the given uci move.
 func (g *Game) MakeUciMove(uci string) error {
-	err := g.Position.MakeUciMove(uci)
+	move, err := g.Position.uciMove(uci)
 	if err != nil {
 		return err
 	}
 
-	g.updateStatus()
+	return g.MakeMove(move)
+}
 
-	return nil
+// MakeSanMove makes a move from the given move in standard algebraic notation.
+func (g *Game) MakeSanMove(san string) error {
+	move, err := g.Position.ParseSan(san)
+	if err != nil {
+		return err
+	}
+
+	return g.MakeMove(move)
 }
 
+// MakeMove makes the move and records it in the game's moves.
 func (g *Game) MakeMove(move Move) error {
 	err := g.Position.MakeMove(move)
 	if err != nil {
 		return err
 	}
 
+	// games copied by value share their moves, so a new move is never added in place
+	g.moves = append(slices.Clip(g.moves), GameMove{Move: move})
 	g.updateStatus()
 
 	return nil
 }
 
+// updateStatus ends the game if the player to move has been checkmated or
+// stalemated or the position is a draw.
 func (g *Game) updateStatus() {
-	opponent := g.Position.turn.OpposingSide()
-	if g.Position.IsCheckmated(opponent) {
-		if opponent == White {
+	turn := g.Position.turn
+	if len(g.Position.GenerateMoves(LegalMoveGeneration)) == 0 {
+		if !g.Position.IsKingInCheck(turn) {
+			g.status = Stalemate
+		} else if turn == White {
 			g.status = WhiteCheckmated
 		} else {
 			g.status = BlackCheckmated
 		}
-	} else if g.Position.IsStalemate(opponent) {
-		g.status = Stalemate
 	} else if g.Position.IsDraw() {
 		g.status = Draw
 	}
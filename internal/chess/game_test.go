@@ -0,0 +1,22 @@
+package chess
+
+import "testing"
+
+func TestGameCopy(t *testing.T) {
+	game, _ := NewGame(StartingFen)
+	game.MakeUciMove("e2e4")
+
+	branch := game
+	branch.MakeUciMove("e7e5")
+	game.MakeUciMove("c7c5")
+
+	if branch.Moves()[1].Move.Uci(false) != "e7e5" || game.Moves()[1].Move.Uci(false) != "c7c5" {
+		t.Fatalf("%s: expected each game to keep its own moves got %s and %s", t.Name(), branch.Moves()[1].Move, game.Moves()[1].Move)
+	}
+
+	branch.Position.Undo()
+	game.Position.Undo()
+	if branch.Position.Fen() != game.Position.Fen() {
+		t.Fatalf("%s: expected both games to undo to %s got %s", t.Name(), game.Position.Fen(), branch.Position.Fen())
+	}
+}
@@ -0,0 +1,108 @@
+package chess
+
+// magic holds what is needed to look up the attacks of a sliding piece on a
+// single square. The occupied squares that can block the piece are
+// multiplied by the magic number to give a unique index into the attacks.
+type magic struct {
+	mask   BitBoard   // The squares whose occupancy changes the attacks, excluding the edges.
+	number uint64     // The magic number that maps each occupancy of the mask to an index.
+	shift  uint       // 64 minus the number of squares in the mask.
+	table  []BitBoard // The attacks for each index.
+}
+
+var rookMagics [64]magic
+var bishopMagics [64]magic
+
+// index returns where the attacks for the occupied squares are in the table.
+func (m *magic) index(occupied BitBoard) uint64 {
+	return (uint64(occupied&m.mask) * m.number) >> m.shift
+}
+
+// initMagics fills the magic bitboard tables. The tables are filled using
+// ray attacks so this is called once the rays are ready.
+func initMagics() {
+	edges := Rank1BB | Rank8BB | FileABB | FileHBB
+
+	for square := A1; square <= H8; square++ {
+		// the edge of the board only blocks the rook along the rank or file it is on
+		rookMask := (getFileRayAttacks(0, square) &^ (Rank1BB | Rank8BB)) | (getRankRayAttacks(0, square) &^ (FileABB | FileHBB))
+		initMagic(&rookMagics[square], square, rookMask, rookMagicNumbers[square], getRookRayAttacks)
+
+		bishopMask := getBishopRayAttacks(0, square) &^ edges
+		initMagic(&bishopMagics[square], square, bishopMask, bishopMagicNumbers[square], getBishopRayAttacks)
+	}
+}
+
+// initMagic fills the table of attacks for every occupancy of the mask.
+func initMagic(m *magic, square Square, mask BitBoard, number uint64, rayAttacks func(BitBoard, Square) BitBoard) {
+	bits := mask.PopulationCount()
+
+	m.mask = mask
+	m.number = number
+	m.shift = uint(64 - bits)
+	m.table = make([]BitBoard, 1<<bits)
+
+	// visit every subset of the mask
+	occupied := BitBoard(0)
+	for {
+		m.table[m.index(occupied)] = rayAttacks(occupied, square)
+
+		occupied = (occupied - mask) & mask
+		if occupied == 0 {
+			break
+		}
+	}
+}
+
+// getRookAttacks returns the squares a rook on the square attacks.
+func getRookAttacks(occupied BitBoard, square Square) BitBoard {
+	m := &rookMagics[square]
+	return m.table[m.index(occupied)]
+}
+
+// getBishopAttacks returns the squares a bishop on the square attacks.
+func getBishopAttacks(occupied BitBoard, square Square) BitBoard {
+	m := &bishopMagics[square]
+	return m.table[m.index(occupied)]
+}
+
+// rookMagicNumbers were found by trying random sparse numbers until one
+// gave every occupancy of the mask an index without a conflicting attack.
+var rookMagicNumbers = [64]uint64{
+	0x0080008050c00024, 0x0040009008200041, 0x0280100081a82000, 0x0080100080080204,
+	0x0080240008001a80, 0x0500240012450008, 0x4280008006002100, 0x0680002140800100,
+	0x4000800080400820, 0x0020400044a01008, 0x004d004020003300, 0x4000801000809800,
+	0x0201800800804400, 0x0009001803000400, 0x0405000100020004, 0x0460800041000080,
+	0x41800a4002452001, 0x0410808040002008, 0x0800410020043100, 0x0440220008120240,
+	0x0d02020020081024, 0x0002808002000400, 0x0410040012d00803, 0x00200e0000640081,
+	0x0200a08480104000, 0x0900200080804000, 0x0800408600120220, 0x0000401200200a01,
+	0x8008000a800c0080, 0x81181400800a0080, 0x08004104000826b0, 0x032138820000590c,
+	0x00b0204003800580, 0x0000288101004000, 0x0100504101002000, 0x0000100101002018,
+	0x0000810400800800, 0x0081040080800200, 0x000005080c001006, 0x12001441020000a4,
+	0x4010400090668000, 0x0030094020004000, 0x1060012641010010, 0x2088001000210100,
+	0x0000850008010010, 0x0004002010040128, 0x240a8122900c0008, 0x4000028104620014,
+	0x408000c0006000c0, 0x4810004002200040, 0x0820043001842080, 0x000a402200102a00,
+	0x9200800800240180, 0x0004020080240080, 0x0400100906080400, 0x0440204085240200,
+	0x008420c680001301, 0x0200824022021302, 0x0000200419c10011, 0x0001000410000821,
+	0x0101002800100a15, 0x080e000804301902, 0x000402a108021004, 0x4000040049008022,
+}
+
+// bishopMagicNumbers were found the same way as rookMagicNumbers.
+var bishopMagicNumbers = [64]uint64{
+	0x104048051c122240, 0x0010820800568110, 0x02140c42820000e0, 0xc4208a0080000200,
+	0x0102021000000006, 0x000602822041000a, 0x6802010c201e0801, 0x0601440409091000,
+	0x811009a208024400, 0x0000020428022044, 0x0200210644004048, 0x110089040101000c,
+	0x0004040504006000, 0x2410610520104210, 0x2301140208240401, 0x1410010048040410,
+	0x0040121150010500, 0x8628400602240400, 0x0008041000444208, 0x6000828802004010,
+	0x0108200402080118, 0x2002900200500803, 0x9000408208224800, 0x0800202216021204,
+	0x1150194340180980, 0x0011200010020200, 0x6112030040840080, 0x0002008088006840,
+	0xc001010008104004, 0x8011020025004100, 0x0004010024011101, 0x490412cc09030284,
+	0x0911213002281049, 0x2021280208081000, 0x4000180400020401, 0x0090020080080080,
+	0x80c0010300020084, 0x8005104200410120, 0x0984140409008280, 0x201c004140020100,
+	0x0001090920024000, 0x8001180104401000, 0x20001a0804004602, 0x3053042011022800,
+	0x3000041040900400, 0x0201481004900100, 0xa2021a2441000413, 0x0008008400428080,
+	0x1091011002208020, 0x4000410410421380, 0x4400042402182400, 0x1808006084044896,
+	0x088820200f440000, 0x0009200410008000, 0x0410200204104000, 0x426018030900e268,
+	0x4020120804342404, 0x0200002208244402, 0x0000880280680801, 0x0214000318420600,
+	0x2a408000210a4400, 0x890100a004012a02, 0x0000400228022a90, 0x8008901300cc0080,
+}
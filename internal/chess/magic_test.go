@@ -0,0 +1,74 @@
+package chess
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func magicTest(t *testing.T, name string, magics *[64]magic, magicAttacks func(BitBoard, Square) BitBoard, rayAttacks func(BitBoard, Square) BitBoard) {
+	random := rand.New(rand.NewSource(1))
+
+	for square := A1; square <= H8; square++ {
+		mask := magics[square].mask
+
+		// every occupancy of the mask
+		occupied := BitBoard(0)
+		for {
+			if magicAttacks(occupied, square) != rayAttacks(occupied, square) {
+				t.Fatalf("%s: expected %s attacks from %s with occupancy %d to match the ray attacks", t.Name(), name, square.ToAlgebraic(), occupied)
+			}
+
+			occupied = (occupied - mask) & mask
+			if occupied == 0 {
+				break
+			}
+		}
+
+		// squares outside of the mask don't change the attacks
+		for i := 0; i < 100; i++ {
+			occupied := BitBoard(random.Uint64() & random.Uint64())
+			if magicAttacks(occupied, square) != rayAttacks(occupied, square) {
+				t.Fatalf("%s: expected %s attacks from %s with occupancy %d to match the ray attacks", t.Name(), name, square.ToAlgebraic(), occupied)
+			}
+		}
+	}
+}
+
+func TestRookMagics(t *testing.T) {
+	magicTest(t, "rook", &rookMagics, getRookAttacks, getRookRayAttacks)
+}
+
+func TestBishopMagics(t *testing.T) {
+	magicTest(t, "bishop", &bishopMagics, getBishopAttacks, getBishopRayAttacks)
+}
+
+// Benchmarks
+
+func slidingAttacksBenchmark(b *testing.B, attacks func(BitBoard, Square) BitBoard) {
+	position, _ := NewPosition("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	occupied := position.whiteBB | position.blackBB
+
+	for i := 0; i < b.N; i++ {
+		for square := A1; square <= H8; square++ {
+			attacks(occupied, square)
+		}
+	}
+}
+
+func BenchmarkSlidingAttacks(b *testing.B) {
+	b.Run("RookMagic", func(b *testing.B) {
+		slidingAttacksBenchmark(b, getRookAttacks)
+	})
+
+	b.Run("RookRay", func(b *testing.B) {
+		slidingAttacksBenchmark(b, getRookRayAttacks)
+	})
+
+	b.Run("BishopMagic", func(b *testing.B) {
+		slidingAttacksBenchmark(b, getBishopAttacks)
+	})
+
+	b.Run("BishopRay", func(b *testing.B) {
+		slidingAttacksBenchmark(b, getBishopRayAttacks)
+	})
+}
@@ -130,12 +130,38 @@ func (m Move) IsIrreversible() bool {
 	return m.HasFlag(PawnPushMoveFlag) || m.IsCapture() || m.IsPromotion()
 }
 
+// KingDestination returns the square the king ends up on for a castling move.
+//
+// Castling moves are stored as the king capturing its own rook so the king's
+// destination is not the square the move goes to.
+func (m Move) KingDestination() Square {
+	file := 3
+	if m.to > m.from {
+		file = 7
+	}
+
+	return SquareFromRankFile(m.from.Rank(), file)
+}
+
+// String returns the move in uci notation, with castling written as the king
+// moving two squares.
 func (m Move) String() string {
+	return m.Uci(false)
+}
+
+// Uci returns the move in uci notation. In chess960 castling is written as the
+// king capturing its own rook, otherwise as the king moving two squares.
+func (m Move) Uci(chess960 bool) string {
 	if m.Type() == Null {
 		return "0000"
 	}
 
-	str := m.from.ToAlgebraic() + m.to.ToAlgebraic()
+	to := m.to
+	if m.Type() == CastleMove && !chess960 {
+		to = m.KingDestination()
+	}
+
+	str := m.from.ToAlgebraic() + to.ToAlgebraic()
 
 	if m.promotionPiece != EmptyPiece {
 		character := string(m.promotionPiece.Character())
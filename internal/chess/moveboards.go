@@ -3,12 +3,12 @@ package chess
 var knightMoves = [64]BitBoard{
 	132096, 329728, 659712, 1319424, 2638848, 5277696, 10489856, 4202496,
 	33816580, 84410376, 168886289, 337772578, 675545156, 1351090312, 2685403152, 1075839008,
-	8657044482, 21609056261, 43234889994, 86469779988, 172939559976, 345879119952, 687463207072, 275423174720,
+	8657044482, 21609056261, 43234889994, 86469779988, 172939559976, 345879119952, 687463207072, 275414786112,
 	2216203387392, 5531918402816, 11068131838464, 22136263676928, 44272527353856, 88545054707712, 175990581010432, 70506185244672,
 	567348067172352, 1416171111120896, 2833441750646784, 5666883501293568, 11333767002587136, 22667534005174272, 45053588738670592, 18049583422636032,
 	145241105196122112, 362539804446949376, 725361088165576704, 1450722176331153408, 2901444352662306816, 5802888705324613632, 11533718717099671552, 4620693356194824192,
-	288234782788157440, 576469569871282176, 1224997833292120064, 2449995666584240128, 4899991333168480256, 9799982666336960512, 1170954182497140736, 2341907265482653696,
-	1128098930098176, 2257297371824128, 4796069720358912, 4796069720358912, 19184278881435648, 38368557762871296, 4679521487814656, 9077567998918656,
+	288234782788157440, 576469569871282176, 1224997833292120064, 2449995666584240128, 4899991333168480256, 9799982666336960512, 1152939783987658752, 2305878468463689728,
+	1128098930098176, 2257297371824128, 4796069720358912, 9592139440717824, 19184278881435648, 38368557762871296, 4679521487814656, 9077567998918656,
 }
 
 var kingMoves = [64]BitBoard{
@@ -88,6 +88,9 @@ func init() {
 	for _, direction := range directions {
 		rayAttacks[direction.rayIndex()][64] = BitBoard(0)
 	}
+
+	initMagics()
+	initLines()
 }
 
 func getPositiveRayAttacks(occupied BitBoard, dir direction, square Square) BitBoard {
@@ -129,10 +132,47 @@ func getAntiDiagonalAttacks(occupied BitBoard, square Square) BitBoard {
 	return getPositiveRayAttacks(occupied, northwest, square) | getNegativeRayAttacks(occupied, southeast, square)
 }
 
-func getRookAttacks(occupied BitBoard, square Square) BitBoard {
+// getRookRayAttacks calculates the rook attacks from the square by scanning
+// each ray. This is used to fill the magic bitboard tables.
+func getRookRayAttacks(occupied BitBoard, square Square) BitBoard {
 	return getFileRayAttacks(occupied, square) | getRankRayAttacks(occupied, square)
 }
 
-func getBishopAttacks(occupied BitBoard, square Square) BitBoard {
+// getBishopRayAttacks calculates the bishop attacks from the square by
+// scanning each ray. This is used to fill the magic bitboard tables.
+func getBishopRayAttacks(occupied BitBoard, square Square) BitBoard {
 	return getDiagonalAttacks(occupied, square) | getAntiDiagonalAttacks(occupied, square)
 }
+
+// betweenSquares are the squares strictly between two squares on the same
+// rank, file or diagonal. Squares that don't share a line have none.
+var betweenSquares [64][64]BitBoard
+
+// lineSquares are all of the squares on the rank, file or diagonal that
+// passes through two squares. Squares that don't share a line have none.
+var lineSquares [64][64]BitBoard
+
+// initLines fills betweenSquares and lineSquares, it uses the sliding
+// attacks so is called once they are ready.
+func initLines() {
+	for from := A1; from <= H8; from++ {
+		for to := A1; to <= H8; to++ {
+			if from == to {
+				continue
+			}
+
+			fromBB := BitBoard(0)
+			fromBB.SetBit(uint64(from))
+			toBB := BitBoard(0)
+			toBB.SetBit(uint64(to))
+
+			if getRookAttacks(0, from)&toBB != 0 {
+				betweenSquares[from][to] = getRookAttacks(toBB, from) & getRookAttacks(fromBB, to)
+				lineSquares[from][to] = (getRookAttacks(0, from) & getRookAttacks(0, to)) | fromBB | toBB
+			} else if getBishopAttacks(0, from)&toBB != 0 {
+				betweenSquares[from][to] = getBishopAttacks(toBB, from) & getBishopAttacks(fromBB, to)
+				lineSquares[from][to] = (getBishopAttacks(0, from) & getBishopAttacks(0, to)) | fromBB | toBB
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package chess
+
+import "testing"
+
+func TestKnightMoves(t *testing.T) {
+	offsets := [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+
+	for square := A1; square <= H8; square++ {
+		expected := BitBoard(0)
+		for _, offset := range offsets {
+			rank := square.Rank() + offset[0]
+			file := square.File() + offset[1]
+			if rank >= 1 && rank <= 8 && file >= 1 && file <= 8 {
+				expected.SetBit(uint64(SquareFromRankFile(rank, file)))
+			}
+		}
+
+		if knightMoves[square] != expected {
+			t.Fatalf("%s: expected knight moves %d from %s got %d", t.Name(), expected, square.ToAlgebraic(), knightMoves[square])
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package chess
 
+import (
+	"fmt"
+	"slices"
+)
+
 type MoveGenerationType uint8
 
 const (
@@ -8,8 +13,7 @@ const (
 )
 
 // generatePawnMoves generates the moves for the pawns on the board
-func generatePawnMoves(position Position, genType MoveGenerationType) []Move {
-	moves := []Move{}
+func generatePawnMoves(position Position, genType MoveGenerationType, list *MoveList) {
 	dir := Square(pawnDirection(position.turn))
 
 	pawnBB := position.pawnBB & position.GetColorBB(position.turn)
@@ -25,12 +29,12 @@ func generatePawnMoves(position Position, genType MoveGenerationType) []Move {
 					move.WithFlags(PawnPushMoveFlag)
 					move.WithPromotion(NewPiece(pieceType, position.turn))
 
-					moves = append(moves, move)
+					list.Add(move)
 				}
 			} else {
 				move := NewMove(square, toSquare, QuietMove)
 				move.WithFlags(PawnPushMoveFlag)
-				moves = append(moves, move)
+				list.Add(move)
 			}
 
 			if square.Rank() == pawnStartingRank(position.turn) {
@@ -38,7 +42,7 @@ func generatePawnMoves(position Position, genType MoveGenerationType) []Move {
 				if !position.IsSquareOccupied(toSquare) {
 					move := NewMove(square, toSquare, QuietMove)
 					move.WithFlags(PawnPushMoveFlag)
-					moves = append(moves, move)
+					list.Add(move)
 				}
 			}
 		}
@@ -55,40 +59,38 @@ func generatePawnMoves(position Position, genType MoveGenerationType) []Move {
 				continue
 			}
 
-			capturePiece, _ := position.GetPieceAt(captureSquare)
+			capturePiece := position.pieceAt(captureSquare)
 			if capturePiece != EmptyPiece && capturePiece.Color() != position.turn {
 				if captureSquare.Rank() == pawnPromotionRank(position.Turn()) {
 					for _, pieceType := range promotablePieces {
 						move := NewMove(square, captureSquare, CaptureMove)
 						move.WithPromotion(NewPiece(pieceType, position.Turn()))
-						moves = append(moves, move)
+						list.Add(move)
 					}
 				} else {
 					move := NewMove(square, captureSquare, CaptureMove)
-					moves = append(moves, move)
+					list.Add(move)
 				}
 			}
 		}
 
 		enPassantSquare := position.EnPassant()
-		if square+dir+Square(east) == enPassantSquare || square+dir+Square(west) == enPassantSquare {
+		// the file distance check stops pawns on the edge files wrapping around the board
+		adjacent := square+dir+Square(east) == enPassantSquare || square+dir+Square(west) == enPassantSquare
+		if position.EnPassantPossible() && adjacent && FileDistance(square, enPassantSquare) == 1 {
 			captureSquare := enPassantSquare + Square(pawnDirection(position.turn.OpposingSide()))
 
-			capturePiece, _ := position.GetPieceAt(captureSquare)
+			capturePiece := position.pieceAt(captureSquare)
 			if capturePiece.Type() == Pawn && capturePiece.Color() == position.Turn().OpposingSide() {
 				move := NewMove(square, position.EnPassant(), EnPassantMove)
-				moves = append(moves, move)
+				list.Add(move)
 			}
 		}
 	}
-
-	return moves
 }
 
 // generateKnightMoves generates the moves for the knights on the board
-func generateKnightMoves(position Position, genType MoveGenerationType) []Move {
-	moves := []Move{}
-
+func generateKnightMoves(position Position, genType MoveGenerationType, list *MoveList) {
 	knightBB := position.knightBB & position.GetColorBB(position.turn)
 
 	occupied := position.whiteBB | position.blackBB
@@ -103,7 +105,7 @@ func generateKnightMoves(position Position, genType MoveGenerationType) []Move {
 			for moveBB > 0 {
 				toSquare := Square(moveBB.PopLsb())
 				move := NewMove(fromSquare, toSquare, QuietMove)
-				moves = append(moves, move)
+				list.Add(move)
 			}
 		}
 
@@ -111,17 +113,13 @@ func generateKnightMoves(position Position, genType MoveGenerationType) []Move {
 		for capturesBB > 0 {
 			toSquare := Square(capturesBB.PopLsb())
 			move := NewMove(fromSquare, toSquare, CaptureMove)
-			moves = append(moves, move)
+			list.Add(move)
 		}
 	}
-
-	return moves
 }
 
 // generateBishopMoves generates the moves for the bishops on the board
-func generateBishopMoves(position Position, pieceBB BitBoard, genType MoveGenerationType) []Move {
-	moves := []Move{}
-
+func generateBishopMoves(position Position, pieceBB BitBoard, genType MoveGenerationType, list *MoveList) {
 	occupied := position.whiteBB | position.blackBB
 	opponent := position.GetColorBB(position.turn.OpposingSide())
 
@@ -134,7 +132,7 @@ func generateBishopMoves(position Position, pieceBB BitBoard, genType MoveGenera
 			for moveBB > 0 {
 				toSquare := Square(moveBB.PopLsb())
 				move := NewMove(fromSquare, toSquare, QuietMove)
-				moves = append(moves, move)
+				list.Add(move)
 			}
 		}
 
@@ -142,17 +140,13 @@ func generateBishopMoves(position Position, pieceBB BitBoard, genType MoveGenera
 		for capturesBB > 0 {
 			toSquare := Square(capturesBB.PopLsb())
 			move := NewMove(fromSquare, toSquare, CaptureMove)
-			moves = append(moves, move)
+			list.Add(move)
 		}
 	}
-
-	return moves
 }
 
 // generateRookMoves generates the moves for the rooks on the board
-func generateRookMoves(position Position, pieceBB BitBoard, genType MoveGenerationType) []Move {
-	moves := []Move{}
-
+func generateRookMoves(position Position, pieceBB BitBoard, genType MoveGenerationType, list *MoveList) {
 	occupied := position.whiteBB | position.blackBB
 	opponent := position.GetColorBB(position.turn.OpposingSide())
 
@@ -165,7 +159,7 @@ func generateRookMoves(position Position, pieceBB BitBoard, genType MoveGenerati
 			for moveBB > 0 {
 				toSquare := Square(moveBB.PopLsb())
 				move := NewMove(fromSquare, toSquare, QuietMove)
-				moves = append(moves, move)
+				list.Add(move)
 			}
 		}
 
@@ -173,29 +167,21 @@ func generateRookMoves(position Position, pieceBB BitBoard, genType MoveGenerati
 		for capturesBB > 0 {
 			toSquare := Square(capturesBB.PopLsb())
 			move := NewMove(fromSquare, toSquare, CaptureMove)
-			moves = append(moves, move)
+			list.Add(move)
 		}
 	}
-
-	return moves
 }
 
 // generateQueenMoves generates the moves for the queens on the board
-func generateQueenMoves(position Position, genType MoveGenerationType) []Move {
+func generateQueenMoves(position Position, genType MoveGenerationType, list *MoveList) {
 	queenBB := position.queenBB & position.GetColorBB(position.turn)
 
-	moves := generateRookMoves(position, queenBB, genType)
-
-	bishopMoves := generateBishopMoves(position, queenBB, genType)
-	moves = append(moves, bishopMoves...)
-
-	return moves
+	generateRookMoves(position, queenBB, genType, list)
+	generateBishopMoves(position, queenBB, genType, list)
 }
 
 // generateKingMoves generates the moves for the kings on the board
-func generateKingMoves(position Position, genType MoveGenerationType, includeCastling bool) []Move {
-	moves := []Move{}
-
+func generateKingMoves(position Position, genType MoveGenerationType, includeCastling bool, list *MoveList) {
 	kingSquare := position.GetKingSquare(position.turn)
 
 	attacks := kingMoves[kingSquare]
@@ -207,7 +193,7 @@ func generateKingMoves(position Position, genType MoveGenerationType, includeCas
 		for moveBB > 0 {
 			toSquare := Square(moveBB.PopLsb())
 			move := NewMove(kingSquare, toSquare, QuietMove)
-			moves = append(moves, move)
+			list.Add(move)
 		}
 	}
 
@@ -215,146 +201,244 @@ func generateKingMoves(position Position, genType MoveGenerationType, includeCas
 	for capturesBB > 0 {
 		toSquare := Square(capturesBB.PopLsb())
 		move := NewMove(kingSquare, toSquare, CaptureMove)
-		moves = append(moves, move)
+		list.Add(move)
 	}
 
 	if includeCastling {
-		if position.turn == White {
-			if position.HasCastlingRights(WhiteCastleKingside) && position.squaresEmpty([]Square{F1, G1}) {
-				move := NewMove(kingSquare, kingSquare+Square(east*2), CastleMove)
-				moves = append(moves, move)
+		for _, kingside := range [2]bool{true, false} {
+			right := castlingRight(position.turn, kingside)
+			if position.canCastle(right) {
+				// castling is stored as the king capturing its own rook
+				move := NewMove(kingSquare, position.CastlingRook(right), CastleMove)
+				list.Add(move)
 			}
+		}
+	}
+}
 
-			if position.HasCastlingRights(WhiteCastleQueenside) && position.squaresEmpty([]Square{D1, C1, B1}) {
-				move := NewMove(kingSquare, kingSquare+Square(west*2), CastleMove)
-				moves = append(moves, move)
-			}
-		} else {
-			if position.HasCastlingRights(BlackCastleKingside) && position.squaresEmpty([]Square{F8, G8}) {
-				move := NewMove(kingSquare, kingSquare+Square(east*2), CastleMove)
-				moves = append(moves, move)
-			}
+// legalityMasks are worked out once for a position so that most moves can
+// be checked without making them.
+type legalityMasks struct {
+	king      Square   // The square of the king of the side to move.
+	checkers  BitBoard // The opposing pieces giving check.
+	checkMask BitBoard // The squares a piece other than the king must move to, capturing or blocking the checking piece.
+	pinned    BitBoard // Pieces that can only move along the line between the king and the piece pinning them.
+	occupied  BitBoard // The occupied squares without the king, so the king can't hide behind itself from a sliding piece.
+}
 
-			if position.HasCastlingRights(BlackCastleQueenside) && position.squaresEmpty([]Square{D8, C8, B8}) {
-				move := NewMove(kingSquare, kingSquare+Square(west*2), CastleMove)
-				moves = append(moves, move)
-			}
+// legalityMasks finds the checking pieces, the squares that stop a check and
+// the pieces pinned to the king of the side to move.
+func (p Position) legalityMasks() legalityMasks {
+	us := p.GetColorBB(p.turn)
+	them := p.GetColorBB(p.turn.OpposingSide())
+	occupied := p.whiteBB | p.blackBB
+
+	king := p.GetKingSquare(p.turn)
+	kingBB := BitBoard(0)
+	kingBB.SetBit(uint64(king))
+
+	masks := legalityMasks{
+		king:      king,
+		checkers:  p.attackersOfColor(king, p.turn.OpposingSide(), occupied),
+		checkMask: ^BitBoard(0),
+		occupied:  occupied &^ kingBB,
+	}
+
+	switch masks.checkers.PopulationCount() {
+	case 0:
+		break
+	case 1:
+		checker := Square(masks.checkers.Lsb())
+		masks.checkMask = masks.checkers | betweenSquares[king][checker]
+		break
+	default:
+		// only the king can move out of a double check
+		masks.checkMask = 0
+		break
+	}
+
+	// sliding pieces that would attack the king if only our pieces were removed
+	snipers := getRookAttacks(them, king) & (p.rookBB | p.queenBB) & them
+	snipers |= getBishopAttacks(them, king) & (p.bishopBB | p.queenBB) & them
+
+	for snipers > 0 {
+		sniper := Square(snipers.PopLsb())
+
+		blockers := betweenSquares[king][sniper] & occupied
+		if blockers.PopulationCount() == 1 && blockers&us != 0 {
+			masks.pinned |= blockers
 		}
 	}
 
-	return moves
+	return masks
 }
 
-// isLegalMove checks that the move would not result in an illegal position.
-func (p Position) isLegalMove(move Move) bool {
-	// check that the squares in between the king and rook are not attacked
+// isLegal checks that the move does not leave the king in check using the
+// legality masks. Castling and en passant move more than one piece so they
+// are checked against the board as it will be after the move.
+func (p Position) isLegal(move Move, masks legalityMasks) bool {
 	if move.Type() == CastleMove {
-		direction := east
-		if move.To() == C1 || move.To() == C8 {
-			direction = west
-		}
+		return p.isLegalCastle(move)
+	}
 
-		difference := move.FileDifference()
-		for i := 0; i <= difference; i++ {
-			square := move.From() + Square(difference*int(direction))
-			if p.IsSquareAttackedBy(square, p.turn.OpposingSide()) {
-				return false
-			}
-		}
+	if move.Type() == EnPassantMove {
+		return p.isLegalEnPassant(move, masks)
+	}
 
-		return true
+	from := move.From()
+	to := move.To()
+
+	if from == masks.king {
+		return p.attackersOfColor(to, p.turn.OpposingSide(), masks.occupied) == 0
 	}
 
-	// check that after the move is made that the king is not in check
-	p.MakeMove(move)
-	inCheck := p.IsKingInCheck(p.turn.OpposingSide())
-	p.Undo()
+	toBB := BitBoard(0)
+	toBB.SetBit(uint64(to))
 
-	return !inCheck
+	if masks.checkMask&toBB == 0 {
+		return false
+	}
+
+	// a pinned piece can still move along the line of the pin
+	if masks.pinned.IsBitSet(uint64(from)) && lineSquares[masks.king][from]&toBB == 0 {
+		return false
+	}
+
+	return true
+}
+
+// isLegalCastle checks that none of the squares the king passes through are
+// attacked, and that the king isn't attacked once it and the rook have moved.
+func (p Position) isLegalCastle(move Move) bool {
+	them := p.turn.OpposingSide()
+	occupied := p.whiteBB | p.blackBB
+
+	kingDestination, rookDestination := castlingDestinations(castlingRight(p.turn, move.To() > move.From()))
+
+	step := Square(0)
+	if kingDestination > move.From() {
+		step = Square(east)
+	} else if kingDestination < move.From() {
+		step = Square(west)
+	}
+
+	for square := move.From(); square != kingDestination; square += step {
+		if p.attackersOfColor(square, them, occupied) != 0 {
+			return false
+		}
+	}
+
+	// in chess960 the rook can be what was stopping a piece attacking the king's destination
+	after := occupied
+	after.ClearBit(uint64(move.From()))
+	after.ClearBit(uint64(move.To()))
+	after.SetBit(uint64(kingDestination))
+	after.SetBit(uint64(rookDestination))
+
+	return p.attackersOfColor(kingDestination, them, after) == 0
+}
+
+// isLegalEnPassant checks that the king isn't attacked once both the pawn
+// making the capture and the captured pawn have left their squares.
+func (p Position) isLegalEnPassant(move Move, masks legalityMasks) bool {
+	captureSquare := move.To() + Square(pawnDirection(p.turn.OpposingSide()))
+
+	captured := BitBoard(0)
+	captured.SetBit(uint64(captureSquare))
+
+	after := p.whiteBB | p.blackBB
+	after.ClearBit(uint64(move.From()))
+	after.ClearBit(uint64(captureSquare))
+	after.SetBit(uint64(move.To()))
+
+	// the captured pawn can't attack the king once it is off the board
+	return p.attackersOfColor(masks.king, p.turn.OpposingSide(), after)&^captured == 0
 }
 
-func (position Position) generateLegalMoves() []Move {
-	moves := []Move{}
+// generateLegalMoves adds the legal moves in the position to the list.
+func (position Position) generateLegalMoves(list *MoveList) {
+	masks := position.legalityMasks()
 
-	checkers := position.NumberOfCheckers(position.turn)
+	checkers := masks.checkers.PopulationCount()
 	if checkers < 2 {
 		colorBB := position.GetColorBB(position.turn)
 
-		pawnMoves := generatePawnMoves(position, LegalMoveGeneration)
-		moves = append(moves, pawnMoves...)
-
-		knightMoves := generateKnightMoves(position, LegalMoveGeneration)
-		moves = append(moves, knightMoves...)
+		generatePawnMoves(position, LegalMoveGeneration, list)
+		generateKnightMoves(position, LegalMoveGeneration, list)
 
 		bishopBB := position.GetPieceBB(Bishop)
-		bishopMoves := generateBishopMoves(position, bishopBB&colorBB, LegalMoveGeneration)
-		moves = append(moves, bishopMoves...)
+		generateBishopMoves(position, bishopBB&colorBB, LegalMoveGeneration, list)
 
 		rookBB := position.GetPieceBB(Rook)
-		rookMoves := generateRookMoves(position, rookBB&colorBB, LegalMoveGeneration)
-		moves = append(moves, rookMoves...)
+		generateRookMoves(position, rookBB&colorBB, LegalMoveGeneration, list)
 
-		queenMoves := generateQueenMoves(position, LegalMoveGeneration)
-		moves = append(moves, queenMoves...)
+		generateQueenMoves(position, LegalMoveGeneration, list)
 	}
 
 	inCheck := checkers != 0
-	kingMoves := generateKingMoves(position, LegalMoveGeneration, !inCheck)
-	moves = append(moves, kingMoves...)
-
-	legalMoves := []Move{}
-	for _, move := range moves {
-		if position.isLegalMove(move) {
-			legalMoves = append(legalMoves, move)
-		}
-	}
+	generateKingMoves(position, LegalMoveGeneration, !inCheck, list)
 
-	return legalMoves
+	position.filterLegal(list, masks)
 }
 
-func (position Position) generateAttackMoves() []Move {
-	moves := []Move{}
+// generateAttackMoves adds the legal captures in the position to the list.
+func (position Position) generateAttackMoves(list *MoveList) {
+	masks := position.legalityMasks()
 
 	colorBB := position.GetColorBB(position.turn)
 
-	pawnMoves := generatePawnMoves(position, CaptureMoveGeneration)
-	moves = append(moves, pawnMoves...)
-
-	knightMoves := generateKnightMoves(position, CaptureMoveGeneration)
-	moves = append(moves, knightMoves...)
+	generatePawnMoves(position, CaptureMoveGeneration, list)
+	generateKnightMoves(position, CaptureMoveGeneration, list)
 
 	bishopBB := position.GetPieceBB(Bishop)
-	bishopMoves := generateBishopMoves(position, bishopBB&colorBB, CaptureMoveGeneration)
-	moves = append(moves, bishopMoves...)
+	generateBishopMoves(position, bishopBB&colorBB, CaptureMoveGeneration, list)
 
 	rookBB := position.GetPieceBB(Rook)
-	rookMoves := generateRookMoves(position, rookBB&colorBB, CaptureMoveGeneration)
-	moves = append(moves, rookMoves...)
+	generateRookMoves(position, rookBB&colorBB, CaptureMoveGeneration, list)
 
-	queenMoves := generateQueenMoves(position, CaptureMoveGeneration)
-	moves = append(moves, queenMoves...)
+	generateQueenMoves(position, CaptureMoveGeneration, list)
+	generateKingMoves(position, CaptureMoveGeneration, false, list)
 
-	kingMoves := generateKingMoves(position, CaptureMoveGeneration, false)
-	moves = append(moves, kingMoves...)
+	position.filterLegal(list, masks)
+}
 
-	legalMoves := []Move{}
-	for _, move := range moves {
-		if position.isLegalMove(move) {
-			legalMoves = append(legalMoves, move)
+// filterLegal removes the moves that leave the king in check from the list,
+// keeping the legal moves in the order they were generated.
+func (position Position) filterLegal(list *MoveList, masks legalityMasks) {
+	legal := 0
+	for i := 0; i < list.length; i++ {
+		move := list.moves[i]
+		if position.isLegal(move, masks) {
+			list.moves[legal] = move
+			legal++
 		}
 	}
 
-	return legalMoves
+	list.length = legal
 }
 
-// GenerateMoves generates all legal moves in the position.
-func (position Position) GenerateMoves(genType MoveGenerationType) []Move {
+// GenerateMoveList generates the legal moves in the position into the list,
+// replacing any moves that were already in it. Search uses this rather than
+// GenerateMoves so that no memory is allocated for each position.
+func (position Position) GenerateMoveList(genType MoveGenerationType, list *MoveList) {
+	list.Clear()
+
 	switch genType {
 	case LegalMoveGeneration:
-		return position.generateLegalMoves()
+		position.generateLegalMoves(list)
+		break
 	case CaptureMoveGeneration:
-		return position.generateAttackMoves()
+		position.generateAttackMoves(list)
+		break
 	default:
-		panic("Unknown move generation type '%d' passed to GenerateMoves")
+		panic(fmt.Sprintf("unknown move generation type '%d' passed to GenerateMoveList", genType))
 	}
 }
+
+// GenerateMoves generates all legal moves in the position.
+func (position Position) GenerateMoves(genType MoveGenerationType) []Move {
+	var list MoveList
+	position.GenerateMoveList(genType, &list)
+
+	return slices.Clone(list.Moves())
+}
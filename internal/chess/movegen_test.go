@@ -7,8 +7,11 @@ import (
 
 func generateLegalMovesBenchmark(b *testing.B, fen string) {
 	position, _ := NewPosition(fen)
+	var list MoveList
+
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		position.GenerateMoves(LegalMoveGeneration)
+		position.GenerateMoveList(LegalMoveGeneration, &list)
 	}
 }
 
@@ -40,8 +43,99 @@ func BenchmarkGenerateLegalMoves(b *testing.B) {
 
 func BenchmarkGenerateCaptureMoves(b *testing.B) {
 	position, _ := NewPosition(StartingFen)
+	var list MoveList
 
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		position.GenerateMoves(CaptureMoveGeneration)
+		position.GenerateMoveList(CaptureMoveGeneration, &list)
+	}
+}
+
+// isLegalMove checks that the move would not result in an illegal position by
+// making it, which is slow but simple enough to check the legality masks with.
+func (p *Position) isLegalMove(move Move) bool {
+	// check that none of the squares the king passes through are attacked
+	if move.Type() == CastleMove {
+		kingDestination := move.KingDestination()
+
+		step := Square(0)
+		if kingDestination > move.From() {
+			step = Square(east)
+		} else if kingDestination < move.From() {
+			step = Square(west)
+		}
+
+		for square := move.From(); ; square += step {
+			if p.IsSquareAttackedBy(square, p.turn.OpposingSide()) {
+				return false
+			}
+
+			if square == kingDestination {
+				break
+			}
+		}
+	}
+
+	// check that after the move is made that the king is not in check
+	p.MakeMove(move)
+	inCheck := p.IsKingInCheck(p.turn.OpposingSide())
+	p.Undo()
+
+	return !inCheck
+}
+
+// legalMovesTreeTest walks every move to the given depth checking that the
+// legality masks accept exactly the moves that don't leave the king in check
+// once they are made.
+func legalMovesTreeTest(t *testing.T, position Position, depth int) {
+	masks := position.legalityMasks()
+	includeCastling := masks.checkers == 0
+
+	var pseudoLegal MoveList
+	generatePawnMoves(position, LegalMoveGeneration, &pseudoLegal)
+	generateKnightMoves(position, LegalMoveGeneration, &pseudoLegal)
+	generateBishopMoves(position, position.bishopBB&position.GetColorBB(position.turn), LegalMoveGeneration, &pseudoLegal)
+	generateRookMoves(position, position.rookBB&position.GetColorBB(position.turn), LegalMoveGeneration, &pseudoLegal)
+	generateQueenMoves(position, LegalMoveGeneration, &pseudoLegal)
+	generateKingMoves(position, LegalMoveGeneration, includeCastling, &pseudoLegal)
+
+	for _, move := range pseudoLegal.Moves() {
+		if position.isLegal(move, masks) != position.isLegalMove(move) {
+			t.Fatalf("%s: expected the legality of %s to be %v in %s", t.Name(), move, position.isLegalMove(move), position.Fen())
+		}
+	}
+
+	if depth == 0 {
+		return
+	}
+
+	for _, move := range position.GenerateMoves(LegalMoveGeneration) {
+		position.MakeMove(move)
+		legalMovesTreeTest(t, position, depth-1)
+		position.Undo()
+	}
+}
+
+func TestLegalityMasks(t *testing.T) {
+	cases := []struct {
+		Name string
+		Fen  string
+	}{
+		{Name: "Kiwipete", Fen: "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"},
+		{Name: "EnPassantPin", Fen: "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1"},
+		{Name: "Promotions", Fen: "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1"},
+		{Name: "Pins", Fen: "4k3/4r3/8/b7/1B6/2N5/3P4/r2RK2q w - - 0 1"},
+		{Name: "Chess960", Fen: "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			position, err := NewPosition(c.Fen)
+			if err != nil {
+				t.Fatalf("%s: fen %s returned error: %s", t.Name(), c.Fen, err)
+			}
+
+			legalMovesTreeTest(t, position, 2)
+		})
 	}
 }
@@ -0,0 +1,73 @@
+package chess
+
+// MaxMoves is the most moves a MoveList can hold, no legal position has
+// more than 218 moves.
+const MaxMoves = 256
+
+// MoveList holds the moves generated for a position along with a score for
+// each move used to order them. It has a fixed capacity so generating moves
+// into it never allocates.
+type MoveList struct {
+	moves  [MaxMoves]Move
+	scores [MaxMoves]int
+	length int
+}
+
+// Len returns the number of moves in the list.
+func (l *MoveList) Len() int {
+	return l.length
+}
+
+// Move returns the move at the given index.
+func (l *MoveList) Move(index int) Move {
+	return l.moves[index]
+}
+
+// Moves returns the moves in the list. The slice shares the list's storage
+// so it is only valid until the list is changed.
+func (l *MoveList) Moves() []Move {
+	return l.moves[:l.length]
+}
+
+// Score returns the score of the move at the given index.
+func (l *MoveList) Score(index int) int {
+	return l.scores[index]
+}
+
+// SetScore sets the score of the move at the given index.
+func (l *MoveList) SetScore(index int, score int) {
+	l.scores[index] = score
+}
+
+// Add adds a move to the end of the list with a score of zero.
+func (l *MoveList) Add(move Move) {
+	l.moves[l.length] = move
+	l.scores[l.length] = 0
+	l.length++
+}
+
+// Clear removes every move from the list.
+func (l *MoveList) Clear() {
+	l.length = 0
+}
+
+// Swap swaps the moves, and their scores, at the given indexes.
+func (l *MoveList) Swap(i int, j int) {
+	l.moves[i], l.moves[j] = l.moves[j], l.moves[i]
+	l.scores[i], l.scores[j] = l.scores[j], l.scores[i]
+}
+
+// PickBest moves the highest scoring move from the given index onwards to
+// that index and returns it. Picking moves one at a time orders them by
+// score without sorting moves that are never searched because of a cutoff.
+func (l *MoveList) PickBest(index int) Move {
+	best := index
+	for i := index + 1; i < l.length; i++ {
+		if l.scores[i] > l.scores[best] {
+			best = i
+		}
+	}
+
+	l.Swap(index, best)
+	return l.moves[index]
+}
@@ -0,0 +1,49 @@
+package chess
+
+import "testing"
+
+func TestMoveListPickBest(t *testing.T) {
+	var list MoveList
+
+	scores := []int{0, 1000, -50, 2000, 1000}
+	for i, score := range scores {
+		list.Add(NewMove(Square(i), Square(i+8), QuietMove))
+		list.SetScore(i, score)
+	}
+
+	expected := []int{2000, 1000, 1000, 0, -50}
+	for i, score := range expected {
+		move := list.PickBest(i)
+		if list.Score(i) != score {
+			t.Fatalf("%s: expected move %d to have score %d got %d", t.Name(), i, score, list.Score(i))
+		}
+
+		if scores[move.From()] != score {
+			t.Fatalf("%s: expected %s to keep its score of %d", t.Name(), move, scores[move.From()])
+		}
+	}
+}
+
+func TestGenerateMoveList(t *testing.T) {
+	position, _ := NewPosition("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+
+	var list MoveList
+	list.Add(NullMove)
+
+	position.GenerateMoveList(LegalMoveGeneration, &list)
+	if list.Len() != 48 {
+		t.Fatalf("%s: expected 48 moves got %d", t.Name(), list.Len())
+	}
+
+	moves := position.GenerateMoves(LegalMoveGeneration)
+	for i, move := range moves {
+		if list.Move(i) != move {
+			t.Fatalf("%s: expected move %d to be %s got %s", t.Name(), i, move, list.Move(i))
+		}
+	}
+
+	position.GenerateMoveList(CaptureMoveGeneration, &list)
+	if list.Len() != 8 {
+		t.Fatalf("%s: expected 8 captures got %d", t.Name(), list.Len())
+	}
+}
@@ -0,0 +1,55 @@
+package chess
+
+import "testing"
+
+func TestPolyglotKey(t *testing.T) {
+	// the reference keys from the Polyglot book format specification
+	cases := []struct {
+		Name  string
+		Moves []string
+		Key   uint64
+	}{
+		{Name: "StartingPosition", Moves: []string{}, Key: 0x463b96181691fc9c},
+		{Name: "e4", Moves: []string{"e2e4"}, Key: 0x823c9b50fd114196},
+		{Name: "e4d5", Moves: []string{"e2e4", "d7d5"}, Key: 0x0756b94461c50fb0},
+		{Name: "e4d5e5", Moves: []string{"e2e4", "d7d5", "e4e5"}, Key: 0x662fafb965db29d4},
+		{Name: "e4d5e5f5", Moves: []string{"e2e4", "d7d5", "e4e5", "f7f5"}, Key: 0x22a48b5a8e47ff78},
+		{Name: "e4d5e5f5Ke2", Moves: []string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, Key: 0x652a607ca3f242c1},
+		{Name: "e4d5e5f5Ke2Kf7", Moves: []string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, Key: 0x00fdd303c946bdd9},
+		{Name: "a4b5h4b4c4", Moves: []string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, Key: 0x3c8123ea7b067637},
+		{Name: "a4b5h4b4c4bxc3Ra3", Moves: []string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, Key: 0x5c3f9b829b279560},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			position, _ := NewPosition(StartingFen)
+			for _, uci := range c.Moves {
+				err := position.MakeUciMove(uci)
+				if err != nil {
+					t.Fatalf("%s: %s returned error: %s", t.Name(), uci, err)
+				}
+			}
+
+			if position.PolyglotKey() != c.Key {
+				t.Fatalf("%s: expected key %016x got %016x", t.Name(), c.Key, position.PolyglotKey())
+			}
+		})
+	}
+}
+
+func TestPolyglotKeyEnPassant(t *testing.T) {
+	// the en passant square is only part of the key when the capture is possible
+	position, _ := NewPosition("4k3/8/8/8/3p4/8/4P3/4K3 w - - 0 1")
+	position.MakeUciMove("e2e4")
+
+	withoutCapture, _ := NewPosition("4k3/8/8/8/3pP3/8/8/4K3 b - - 0 1")
+	if position.PolyglotKey() == withoutCapture.PolyglotKey() {
+		t.Fatalf("%s: expected the en passant square to change the key", t.Name())
+	}
+
+	position, _ = NewPosition("4k3/8/8/8/4P3/3p4/8/4K3 b - e3 0 1")
+	withoutCapture, _ = NewPosition("4k3/8/8/8/4P3/3p4/8/4K3 b - - 0 1")
+	if position.PolyglotKey() != withoutCapture.PolyglotKey() {
+		t.Fatalf("%s: expected the en passant square to not change the key", t.Name())
+	}
+}
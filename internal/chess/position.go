@@ -3,8 +3,10 @@ package chess
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Position is a representation of the current state of the game.
@@ -21,12 +23,12 @@ type Position struct {
 	queenBB  BitBoard // BitBoard for all queens.
 	kingBB   BitBoard // BitBoard for all kings.
 
-	attackersBB [64]BitBoard // BitBoard for attackers of each square.
-
 	squares [64]Piece // Keeps track of what piece is on each square. Used for faster lookups.
 
 	enPassant               Square         // The square where en passant is posssible.
 	castlingRights          CastlingRights // The current castling rights for both players.
+	castlingRooks           [4]Square      // The square the rook castles from for each castling right.
+	chess960                bool           // Whether castling moves are written as the king capturing its own rook.
 	lastIrreversibleMovePly int            // The ply at which the last irreversible move happened. An irreversible move is a pawn move or capture.
 	fiftyMoveClock          int            // Number of moves since a capture or a pawn has moved. This is stored in half moves.
 	plies                   int            // Number of half moves in the game.
@@ -35,7 +37,26 @@ type Position struct {
 
 	repetitions int // The number of times the current position has ocurred.
 
-	previous *Position // The previous Position.
+	history      []undoRecord // The records needed to undo each move that has been made, the last is the most recent.
+	historyOwner *Position    // The position that can add records to the storage of the history in place.
+}
+
+// historyCapacity is the least number of undo records space is made for when
+// a position gets storage for its history, enough for a search to its
+// deepest ply without growing it.
+const historyCapacity = 64
+
+// undoRecord is what is needed to undo a move that can't be worked out from
+// the move and the position after it.
+type undoRecord struct {
+	move                    Move           // The move that was made, NullMove for null moves.
+	captured                Piece          // The piece that was captured, or the rook when castling.
+	enPassant               Square         // The en passant square before the move.
+	castlingRights          CastlingRights // The castling rights before the move.
+	fiftyMoveClock          int            // The fifty move clock before the move.
+	lastIrreversibleMovePly int            // The ply of the last irreversible move before the move.
+	hash                    uint64         // The hash before the move.
+	repetitions             int            // The number of repetitions before the move.
 }
 
 // NewPositions creates a Position from the given FEN.
@@ -131,25 +152,9 @@ func NewPosition(fen string) (Position, error) {
 	}
 
 	// parse castling rights
-	castlingRights := fenParts[2]
-	if castlingRights == "-" {
-		position.castlingRights = 0
-	} else {
-		if strings.Contains(castlingRights, "K") {
-			position.castlingRights |= WhiteCastleKingside
-		}
-
-		if strings.Contains(castlingRights, "Q") {
-			position.castlingRights |= WhiteCastleQueenside
-		}
-
-		if strings.Contains(castlingRights, "k") {
-			position.castlingRights |= BlackCastleKingside
-		}
-
-		if strings.Contains(castlingRights, "q") {
-			position.castlingRights |= BlackCastleQueenside
-		}
+	err := position.parseCastlingRights(fenParts[2])
+	if err != nil {
+		return Position{}, err
 	}
 
 	// parse en passant square
@@ -186,19 +191,111 @@ func NewPosition(fen string) (Position, error) {
 	position.lastIrreversibleMovePly = position.plies
 
 	position.hash = generateHash(position)
-	position.previous = nil
 
 	if ok, err := position.IsValid(); !ok {
 		return Position{}, err
 	}
 
-	position.updateAttackers()
-
 	return position, nil
 }
 
+// parseCastlingRights parses the castling rights section of a fen.
+//
+// Along with the standard KQkq this accepts the files of the castling rooks
+// as used by Shredder-FEN and X-FEN. KQkq refer to the outermost rook on that
+// side of the king.
+func (p *Position) parseCastlingRights(castlingRights string) error {
+	p.castlingRights = 0
+	p.castlingRooks = defaultCastlingRooks
+
+	if castlingRights == "-" {
+		return nil
+	}
+
+	for _, character := range castlingRights {
+		color := White
+		if unicode.IsLower(character) {
+			color = Black
+		}
+
+		if (p.kingBB & p.GetColorBB(color)) == 0 {
+			return fmt.Errorf("%w: castling rights for %s without a king", ErrInvalidFen, color)
+		}
+
+		king := p.GetKingSquare(color)
+
+		var right CastlingRights
+		var rook Square
+
+		switch upper := unicode.ToUpper(character); upper {
+		case 'K', 'Q':
+			right = castlingRight(color, upper == 'K')
+			rook = p.outermostRook(right)
+			break
+		case 'A', 'B', 'C', 'D', 'E', 'F', 'G', 'H':
+			file := int(upper-'A') + 1
+			rook = SquareFromRankFile(king.Rank(), file)
+			right = castlingRight(color, file > king.File())
+
+			p.chess960 = true
+			break
+		default:
+			return fmt.Errorf("%w: invalid character '%c' in castling rights", ErrInvalidFen, character)
+		}
+
+		p.castlingRights |= right
+		p.castlingRooks[right.index()] = rook
+
+		// a king or rook off of their usual squares can only be chess960
+		if king.File() != 5 || rook != defaultCastlingRooks[right.index()] {
+			p.chess960 = true
+		}
+	}
+
+	return nil
+}
+
+// outermostRook returns the square of the rook furthest from the king on the
+// side of the castling right. If there is no such rook the standard rook
+// square is returned.
+func (p Position) outermostRook(right CastlingRights) Square {
+	color := right.color()
+	king := p.GetKingSquare(color)
+
+	if right.kingside() {
+		for file := 8; file > king.File(); file-- {
+			square := SquareFromRankFile(king.Rank(), file)
+			if p.IsPieceAt(square, Rook, color) {
+				return square
+			}
+		}
+	} else {
+		for file := 1; file < king.File(); file++ {
+			square := SquareFromRankFile(king.Rank(), file)
+			if p.IsPieceAt(square, Rook, color) {
+				return square
+			}
+		}
+	}
+
+	return defaultCastlingRooks[right.index()]
+}
+
 // Fen gets the FEN for the current position.
+//
+// Castling rights are written using X-FEN, which is the same as standard FEN
+// unless a castling rook is not the outermost rook.
 func (p Position) Fen() string {
+	return p.fen(false)
+}
+
+// ShredderFen gets the FEN for the current position using Shredder-FEN, where
+// castling rights are written as the files of the castling rooks.
+func (p Position) ShredderFen() string {
+	return p.fen(true)
+}
+
+func (p Position) fen(shredder bool) string {
 	var builder strings.Builder
 
 	// write the board
@@ -246,20 +343,28 @@ func (p Position) Fen() string {
 	if p.castlingRights == 0 {
 		builder.WriteString("-")
 	} else {
-		if p.HasCastlingRights(WhiteCastleKingside) {
-			builder.WriteString("K")
-		}
+		for _, right := range allCastlingRights {
+			if !p.HasCastlingRights(right) {
+				continue
+			}
 
-		if p.HasCastlingRights(WhiteCastleQueenside) {
-			builder.WriteString("Q")
-		}
+			rook := p.castlingRooks[right.index()]
 
-		if p.HasCastlingRights(BlackCastleKingside) {
-			builder.WriteString("k")
-		}
+			var character rune
+			if !shredder && rook == p.outermostRook(right) {
+				character = 'Q'
+				if right.kingside() {
+					character = 'K'
+				}
+			} else {
+				character = rune('A' + rook.File() - 1)
+			}
 
-		if p.HasCastlingRights(BlackCastleQueenside) {
-			builder.WriteString("q")
+			if right.color() == Black {
+				character = unicode.ToLower(character)
+			}
+
+			builder.WriteRune(character)
 		}
 	}
 
@@ -285,6 +390,56 @@ func (p Position) Fen() string {
 	return builder.String()
 }
 
+// IsChess960 returns whether the position is from a Chess960 game.
+func (p Position) IsChess960() bool {
+	return p.chess960
+}
+
+// SetChess960 sets whether the position is from a Chess960 game. This
+// changes how castling moves are written and read as uci moves.
+func (p *Position) SetChess960(chess960 bool) {
+	p.chess960 = chess960
+}
+
+// CastlingRook returns the square the rook castles from for a single castling right.
+func (p Position) CastlingRook(right CastlingRights) Square {
+	return p.castlingRooks[right.index()]
+}
+
+// removeRookCastlingRights removes the castling rights that use the rook
+// starting on the given square.
+func (p *Position) removeRookCastlingRights(square Square) {
+	for _, right := range allCastlingRights {
+		if p.HasCastlingRights(right) && p.castlingRooks[right.index()] == square {
+			p.castlingRights &= ^right
+		}
+	}
+}
+
+// canCastle returns whether every square the king and rook pass through when
+// castling with the given right is empty. It does not check whether the king
+// passes through check.
+func (p Position) canCastle(right CastlingRights) bool {
+	if !p.HasCastlingRights(right) {
+		return false
+	}
+
+	king := p.GetKingSquare(right.color())
+	rook := p.castlingRooks[right.index()]
+	kingDestination, rookDestination := castlingDestinations(right)
+
+	from := min(king, rook, kingDestination, rookDestination)
+	to := max(king, rook, kingDestination, rookDestination)
+
+	for square := from; square <= to; square++ {
+		if square != king && square != rook && p.IsSquareOccupied(square) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // HasCastlingRights checks if the given castling rights are available.
 func (p Position) HasCastlingRights(rights CastlingRights) bool {
 	return (p.castlingRights & rights) > 0
@@ -317,6 +472,13 @@ func (p Position) GetPieceAt(square Square) (Piece, error) {
 	return p.squares[square], nil
 }
 
+// pieceAt returns the piece at the given square, or EmptyPiece if there isn't
+// one. Unlike GetPieceAt it doesn't build an error for empty squares so it is
+// used when making moves and generating them.
+func (p Position) pieceAt(square Square) Piece {
+	return p.squares[square]
+}
+
 // GetKingSquare returns the square of the specified color's King is on.
 func (p Position) GetKingSquare(color Color) Square {
 	kingBB := p.GetColorBB(color) & p.kingBB
@@ -325,7 +487,7 @@ func (p Position) GetKingSquare(color Color) Square {
 
 // IsPieceAt returns whether a piece matching the piece type and color are
 func (p Position) IsPieceAt(square Square, pieceType PieceType, color Color) bool {
-	piece, _ := p.GetPieceAt(square)
+	piece := p.pieceAt(square)
 	return piece.Type() == pieceType && piece.Color() == color
 }
 
@@ -388,18 +550,6 @@ func (p Position) GetPieceBB(pieceType PieceType) BitBoard {
 	panic(fmt.Sprintf("requested bitboard for unknown piece type: %d", pieceType))
 }
 
-// squaresEmpty returns whether all squares are empty.
-func (p Position) squaresEmpty(squares []Square) bool {
-	for _, square := range squares {
-		piece, _ := p.GetPieceAt(square)
-		if piece != EmptyPiece {
-			return false
-		}
-	}
-
-	return true
-}
-
 // IsValid returns whether the position is playable, i.e no more than 8 pawns, one king, etc.
 func (p Position) IsValid() (bool, error) {
 	// check that neither side has more than 16 pieces or zero pieces
@@ -480,6 +630,7 @@ func (p *Position) setPiece(square Square, piece Piece) {
 	}
 
 	p.squares[square] = piece
+	p.hash ^= pieceHash(piece, square)
 
 	index := uint64(square)
 
@@ -518,12 +669,13 @@ func (p *Position) clearPiece(square Square) {
 		panic(fmt.Sprintf("invalid square '%d' passed to clearPiece", square))
 	}
 
-	piece, _ := p.GetPieceAt(square)
+	piece := p.pieceAt(square)
 	if piece == EmptyPiece {
 		panic(fmt.Sprintf("trying to clear a square %s with no piece", square))
 	}
 
 	p.squares[square] = EmptyPiece
+	p.hash ^= pieceHash(piece, square)
 
 	index := uint64(square)
 
@@ -568,15 +720,18 @@ func (p *Position) MakeMove(move Move) error {
 		return fmt.Errorf("%w: tyring to move opponent's piece with %s", ErrInvalidMove, move)
 	}
 
-	capturePiece, _ := p.GetPieceAt(move.To())
-	if movingPiece.Color() == capturePiece.Color() {
+	capturePiece := p.pieceAt(move.To())
+	if movingPiece.Color() == capturePiece.Color() && move.Type() != CastleMove {
 		return fmt.Errorf("%w: trying to capture piece of same color with %s", ErrInvalidMove, move)
 	}
 
 	to := move.To()
 	from := move.From()
 
-	copy := p.Copy()
+	p.pushUndoRecord(move, capturePiece)
+
+	// the state is hashed out here and back in once the move has been made
+	p.hash ^= stateHash(p)
 
 	p.enPassant = -1   // clear en passant square, this will be set later if needed
 	p.fiftyMoveClock++ // increment the fifty move clock, this will be cleared later if needed
@@ -587,38 +742,13 @@ func (p *Position) MakeMove(move Move) error {
 			opposingSide := p.turn.OpposingSide()
 
 			// check to see if a pawn is on a valid square for en passant
-			westPawn, _ := p.GetPieceAt(to + Square(west))
-			eastPawn, _ := p.GetPieceAt(to + Square(east))
+			westPawn := p.pieceAt(to + Square(west))
+			eastPawn := p.pieceAt(to + Square(east))
 			if (westPawn.Type() == Pawn && westPawn.Color() == opposingSide && to.File() != 1) || (eastPawn.Type() == Pawn && eastPawn.Color() == opposingSide && to.File() != 8) {
 				p.enPassant = to + Square(pawnDirection(opposingSide))
 			}
 		}
 
-		if movingPiece.Type() == Rook {
-			switch from {
-			case A1:
-				p.castlingRights &= ^WhiteCastleQueenside
-				break
-			case A8:
-				p.castlingRights &= ^BlackCastleQueenside
-				break
-			case H1:
-				p.castlingRights &= ^WhiteCastleKingside
-				break
-			case H8:
-				p.castlingRights &= ^BlackCastleKingside
-				break
-			}
-		}
-
-		if movingPiece.Type() == King {
-			if p.turn == White {
-				p.castlingRights &= ^WhiteCastleBoth
-			} else {
-				p.castlingRights &= ^BlackCastleBoth
-			}
-		}
-
 		p.clearPiece(from)
 		p.setPiece(to, movingPiece)
 		break
@@ -627,58 +757,18 @@ func (p *Position) MakeMove(move Move) error {
 		p.clearPiece(from)
 
 		p.setPiece(to, movingPiece)
-
-		if capturePiece.Type() == Rook {
-			switch to {
-			case A1:
-				p.castlingRights &= ^WhiteCastleQueenside
-				break
-			case A8:
-				p.castlingRights &= ^BlackCastleQueenside
-				break
-			case H1:
-				p.castlingRights &= ^WhiteCastleKingside
-				break
-			case H8:
-				p.castlingRights &= ^BlackCastleKingside
-				break
-			}
-		}
 		break
 	case CastleMove:
-		// move the king to it's new square
-		p.clearPiece(from)
-		p.setPiece(to, movingPiece)
+		// castling moves are stored as the king capturing its own rook
+		kingDestination, rookDestination := castlingDestinations(castlingRight(p.turn, to > from))
 
-		// move the rook to it's new square
-		switch to {
-		case C1:
-			rook, _ := p.GetPieceAt(A1)
-			p.clearPiece(A1)
-			p.setPiece(D1, rook)
-			break
-		case G1:
-			rook, _ := p.GetPieceAt(H1)
-			p.clearPiece(H1)
-			p.setPiece(F1, rook)
-			break
-		case C8:
-			rook, _ := p.GetPieceAt(A8)
-			p.clearPiece(A8)
-			p.setPiece(D8, rook)
-			break
-		case G8:
-			rook, _ := p.GetPieceAt(H8)
-			p.clearPiece(H8)
-			p.setPiece(F8, rook)
-			break
-		}
+		// the king and rook can end up on each other's squares in chess960
+		// so both are removed before being placed again
+		p.clearPiece(from)
+		p.clearPiece(to)
 
-		if p.turn == White {
-			p.castlingRights &= ^WhiteCastleBoth
-		} else {
-			p.castlingRights &= ^BlackCastleBoth
-		}
+		p.setPiece(kingDestination, movingPiece)
+		p.setPiece(rookDestination, capturePiece)
 		break
 	case EnPassantMove:
 		// move the pawn to it's new square
@@ -691,6 +781,15 @@ func (p *Position) MakeMove(move Move) error {
 		break
 	}
 
+	// moving the king or a castling rook, or capturing a castling rook, loses those castling rights
+	if movingPiece.Type() == King {
+		p.castlingRights &= ^castlingRight(p.turn, true)
+		p.castlingRights &= ^castlingRight(p.turn, false)
+	}
+
+	p.removeRookCastlingRights(from)
+	p.removeRookCastlingRights(to)
+
 	if move.IsPromotion() {
 		p.clearPiece(to) // remove the original piece
 
@@ -703,8 +802,6 @@ func (p *Position) MakeMove(move Move) error {
 		p.fiftyMoveClock = 0
 	}
 
-	p.updateAttackers()
-
 	p.plies++
 
 	if move.IsIrreversible() {
@@ -712,57 +809,85 @@ func (p *Position) MakeMove(move Move) error {
 	}
 
 	p.repetitions = 0
-	p.hash = generateHash(*p)
 	p.turn = p.turn.OpposingSide()
-	p.previous = &copy
+	p.hash ^= stateHash(p)
 
 	// determine the number of times this position has been reached
-	numPlies := p.plies - p.lastIrreversibleMovePly
-	previous := p.previous
-	for i := 0; i < numPlies; i++ {
-		if previous == nil {
-			break
-		}
-
-		if previous.hash == p.hash {
+	numPlies := min(p.plies-p.lastIrreversibleMovePly, len(p.history))
+	for i := 1; i <= numPlies; i++ {
+		if p.history[len(p.history)-i].hash == p.hash {
 			p.repetitions++
 		}
-
-		previous = previous.previous
 	}
 
 	return nil
 }
 
+// pushUndoRecord saves the state needed to undo the move before it is made.
+func (p *Position) pushUndoRecord(move Move, captured Piece) {
+	// positions copied by value share the storage of their history, so a copy
+	// moves its history to storage of its own before adding to it
+	if p.historyOwner != p {
+		history := make([]undoRecord, len(p.history), max(2*len(p.history), historyCapacity))
+		copy(history, p.history)
+
+		p.history = history
+		p.historyOwner = p
+	}
+
+	p.history = append(p.history, undoRecord{
+		move:                    move,
+		captured:                captured,
+		enPassant:               p.enPassant,
+		castlingRights:          p.castlingRights,
+		fiftyMoveClock:          p.fiftyMoveClock,
+		lastIrreversibleMovePly: p.lastIrreversibleMovePly,
+		hash:                    p.hash,
+		repetitions:             p.repetitions,
+	})
+}
+
 // MakeUciMove makes a move from the given uci string.
 func (p *Position) MakeUciMove(uci string) error {
+	move, err := p.uciMove(uci)
+	if err != nil {
+		return err
+	}
+
+	return p.MakeMove(move)
+}
+
+// uciMove creates the move described by the given uci string.
+func (p Position) uciMove(uci string) (Move, error) {
 	if len(uci) < 4 {
-		return errors.New(fmt.Sprintf("invalid move: provided uci: '%s' is too short", uci))
+		return NullMove, errors.New(fmt.Sprintf("invalid move: provided uci: '%s' is too short", uci))
 	}
 
 	from, err := SquareFromAlgebraic(uci[:2])
 	if err != nil {
-		return err
+		return NullMove, err
 	}
 
 	to, err := SquareFromAlgebraic(uci[2:4])
 	if err != nil {
-		return err
+		return NullMove, err
 	}
 
 	movingPiece, err := p.GetPieceAt(from)
 	if err != nil {
-		return err
+		return NullMove, err
 	}
 
 	moveType := QuietMove
 	flags := NoMoveFlag
 
 	if movingPiece.Type() == King {
-		switch uci {
-		case "e1g1", "e1c1", "e8g8", "e8c8":
+		target, _ := p.GetPieceAt(to)
+		if target.Type() == Rook && target.Color() == movingPiece.Color() {
+			moveType = CastleMove // the king capturing its own rook is how chess960 castling is written
+		} else if !p.chess960 && FileDistance(from, to) == 2 {
 			moveType = CastleMove
-			break
+			to = p.castlingRooks[castlingRight(p.turn, to > from).index()]
 		}
 	} else if movingPiece.Type() == Pawn && to == p.enPassant {
 		moveType = EnPassantMove
@@ -771,7 +896,7 @@ func (p *Position) MakeUciMove(uci string) error {
 	}
 
 	capturePiece, _ := p.GetPieceAt(to)
-	if capturePiece != EmptyPiece {
+	if capturePiece != EmptyPiece && moveType != CastleMove {
 		moveType = CaptureMove
 	}
 
@@ -798,37 +923,25 @@ func (p *Position) MakeUciMove(uci string) error {
 		}
 	}
 
-	return p.MakeMove(move)
+	return move, nil
 }
 
 // MakeNullMove switches sides without making an actual move.
 func (p *Position) MakeNullMove() {
-	copy := p.Copy()
+	p.pushUndoRecord(NullMove, EmptyPiece)
+
+	p.hash ^= stateHash(p)
 
 	p.enPassant = -1
 	p.plies++
 
 	p.turn = p.turn.OpposingSide()
-
-	p.previous = &copy
-}
-
-// updateAttackers updates the BitBoard that keeps track of attackers.
-func (p *Position) updateAttackers() {
-	p.attackersBB = [64]BitBoard{}
-
-	ourAttacks := getAttackers(*p, p.turn)
-	theirAttacks := getAttackers(*p, p.turn.OpposingSide())
-
-	moves := append(ourAttacks, theirAttacks...)
-	for _, move := range moves {
-		p.attackersBB[move.To()].SetBit(uint64(move.From()))
-	}
+	p.hash ^= stateHash(p)
 }
 
 // IsSquareAttackedBy returns whether the given square is being attacked by the given color.
 func (p Position) IsSquareAttackedBy(square Square, color Color) bool {
-	return (p.GetAttackers(square) & p.GetColorBB(color)) != BitBoard(0)
+	return p.attackersOfColor(square, color, p.whiteBB|p.blackBB) != BitBoard(0)
 }
 
 // IsSquareAttacked returns whether the given square is attacked.
@@ -849,29 +962,26 @@ func (p Position) NumberOfCheckers(color Color) int {
 	}
 
 	kingSquare := p.GetKingSquare(color)
-	attackers := p.attackersBB[kingSquare] & p.GetColorBB(color.OpposingSide())
+	attackers := p.attackersOfColor(kingSquare, color.OpposingSide(), p.whiteBB|p.blackBB)
 
 	return attackers.PopulationCount()
 }
 
-// IsCheckmated returns whether the specified color has been checkmated.
+// IsCheckmated returns whether the specified color has been checkmated, i.e. it is in check with no legal moves.
 func (p Position) IsCheckmated(color Color) bool {
 	if !p.IsKingInCheck(color) { // king must be in check to be checkmated
 		return false
 	}
 
-	kingSquare := p.GetKingSquare(color)
-	squares := SurroundingSquares(kingSquare)
-	for _, square := range squares {
-		piece, _ := p.GetPieceAt(square)
-
-		// check that the surrounding square is not attacked and not occupied by one of our pieces
-		if !p.IsSquareAttackedBy(square, color.OpposingSide()) && piece.Color() != color {
-			return false
-		}
+	// switch to the color's turn if it is not currently their turn, only this copy is changed
+	if p.turn != color {
+		p.turn = color
+		p.enPassant = -1
 	}
 
-	return true
+	var moves MoveList
+	p.GenerateMoveList(LegalMoveGeneration, &moves)
+	return moves.Len() == 0
 }
 
 // IsDraw returns whether the position is a draw.
@@ -889,19 +999,20 @@ func (p Position) IsStalemate(color Color) bool {
 		return false
 	}
 
-	// temporarily switch to the color's turn if it is not currently their turn
+	// switch to the color's turn if it is not currently their turn, only this copy is changed
 	if p.turn != color {
-		p.MakeNullMove()
-		defer p.Undo()
+		p.turn = color
+		p.enPassant = -1
 	}
 
-	moves := p.GenerateMoves(LegalMoveGeneration)
-	return len(moves) == 0
+	var moves MoveList
+	p.GenerateMoveList(LegalMoveGeneration, &moves)
+	return moves.Len() == 0
 }
 
 // GetAttackers returns a BitBoard containing all pieces attacking the given Square.
 func (p Position) GetAttackers(square Square) BitBoard {
-	return p.attackersBB[square]
+	return p.attackersOf(square, p.whiteBB|p.blackBB)
 }
 
 // Hash returns the hash for the current position.
@@ -931,56 +1042,88 @@ func (p Position) Phase() Phase {
 }
 
 // Copy creates a copy of the current position.
+//
+// It is the same as copying the position by value, the copy gets storage of
+// its own for its history the first time it makes a move.
 func (p Position) Copy() Position {
-	copy := Position{
-		turn:                    p.turn,
-		whiteBB:                 p.whiteBB,
-		blackBB:                 p.blackBB,
-		pawnBB:                  p.pawnBB,
-		bishopBB:                p.bishopBB,
-		knightBB:                p.knightBB,
-		rookBB:                  p.rookBB,
-		queenBB:                 p.queenBB,
-		kingBB:                  p.kingBB,
-		attackersBB:             p.attackersBB,
-		squares:                 p.squares,
-		enPassant:               p.enPassant,
-		castlingRights:          p.castlingRights,
-		fiftyMoveClock:          p.fiftyMoveClock,
-		lastIrreversibleMovePly: p.lastIrreversibleMovePly,
-		plies:                   p.plies,
-		hash:                    p.hash,
-		repetitions:             p.repetitions,
-		previous:                p.previous,
-	}
-
-	return copy
+	return p
 }
 
-// CanUndo returns if there is a previous Position that can be restored to.
+// CanUndo returns if there is a move that can be undone.
 func (p Position) CanUndo() bool {
-	return p.previous != nil
+	return len(p.history) > 0
 }
 
-// Undo restores the Position to the previous Position.
+// Undo takes back the last move, or null move, that was made.
 func (p *Position) Undo() {
-	p.turn = p.previous.turn
-	p.whiteBB = p.previous.whiteBB
-	p.blackBB = p.previous.blackBB
-	p.pawnBB = p.previous.pawnBB
-	p.bishopBB = p.previous.bishopBB
-	p.knightBB = p.previous.knightBB
-	p.rookBB = p.previous.rookBB
-	p.queenBB = p.previous.queenBB
-	p.kingBB = p.previous.kingBB
-	p.attackersBB = p.previous.attackersBB
-	p.squares = p.previous.squares
-	p.enPassant = p.previous.enPassant
-	p.castlingRights = p.previous.castlingRights
-	p.fiftyMoveClock = p.previous.fiftyMoveClock
-	p.lastIrreversibleMovePly = p.previous.lastIrreversibleMovePly
-	p.plies = p.previous.plies
-	p.hash = p.previous.hash
-	p.repetitions = p.previous.repetitions
-	p.previous = p.previous.previous
+	p.UndoInPlace()
+
+	// copies of the position may still need the record that was taken back,
+	// so the next move made gets new storage instead of writing over it
+	p.history = slices.Clip(p.history)
+}
+
+// UndoInPlace takes back the last move, or null move, like Undo but lets
+// the next move made reuse the storage of its record.
+//
+// A copy of the position made since that move would have its history
+// written over, so this is only for code that makes and takes back moves on
+// a position nothing else has a copy of, such as a search.
+func (p *Position) UndoInPlace() {
+	record := p.history[len(p.history)-1]
+	p.history = p.history[:len(p.history)-1]
+
+	p.turn = p.turn.OpposingSide()
+	p.plies--
+
+	move := record.move
+	if move != NullMove {
+		p.unmakeMove(move, record.captured)
+	}
+
+	p.enPassant = record.enPassant
+	p.castlingRights = record.castlingRights
+	p.fiftyMoveClock = record.fiftyMoveClock
+	p.lastIrreversibleMovePly = record.lastIrreversibleMovePly
+	p.hash = record.hash
+	p.repetitions = record.repetitions
+}
+
+// unmakeMove moves the pieces back to where they were before the move was
+// made by the side to move.
+func (p *Position) unmakeMove(move Move, captured Piece) {
+	from := move.From()
+	to := move.To()
+
+	switch move.Type() {
+	case CastleMove:
+		kingDestination, rookDestination := castlingDestinations(castlingRight(p.turn, to > from))
+
+		// the king and rook can end up on each other's squares in chess960
+		p.clearPiece(kingDestination)
+		p.clearPiece(rookDestination)
+
+		p.setPiece(from, NewPiece(King, p.turn))
+		p.setPiece(to, captured)
+		return
+	case EnPassantMove:
+		p.clearPiece(to)
+		p.setPiece(from, NewPiece(Pawn, p.turn))
+
+		captureSquare := to + Square(pawnDirection(p.turn.OpposingSide()))
+		p.setPiece(captureSquare, NewPiece(Pawn, p.turn.OpposingSide()))
+		return
+	}
+
+	movingPiece := p.squares[to]
+	if move.IsPromotion() {
+		movingPiece = NewPiece(Pawn, p.turn)
+	}
+
+	p.clearPiece(to)
+	p.setPiece(from, movingPiece)
+
+	if captured != EmptyPiece {
+		p.setPiece(to, captured)
+	}
 }
@@ -1,6 +1,7 @@
 package chess
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -23,23 +24,6 @@ func TestIsSquareOccupied(t *testing.T) {
 	isSquareOccupiedTest(t, position, B6, false)
 }
 
-func squaresEmptyTest(t *testing.T, position Position, squares []Square, expected bool) {
-	empty := position.squaresEmpty(squares)
-	if empty != expected {
-		t.Fatalf("%s: expected squares '%s' have empty status of '%v' got '%v'", t.Name(), squares, expected, empty)
-	}
-}
-
-func TestSquaresEmpty(t *testing.T) {
-	position, _ := NewPosition(StartingFen)
-
-	squaresEmptyTest(t, position, []Square{A1, A2, A3}, false)
-	squaresEmptyTest(t, position, []Square{A2, A3, B2, B3}, false)
-
-	squaresEmptyTest(t, position, []Square{D4, E4, D5, E5}, true)
-	squaresEmptyTest(t, position, []Square{A3, A4, G5}, true)
-}
-
 func getPieceAtTest(t *testing.T, algebraic string, expectedType PieceType, expectedColor Color) {
 	position, _ := NewPosition(StartingFen)
 	square, _ := SquareFromAlgebraic(algebraic)
@@ -87,6 +71,65 @@ func TestFen(t *testing.T) {
 	}
 }
 
+func TestShredderFen(t *testing.T) {
+	cases := []struct {
+		Fen         string
+		XFen        string
+		ShredderFen string
+		Chess960    bool
+	}{
+		{
+			Fen:         StartingFen,
+			XFen:        StartingFen,
+			ShredderFen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1",
+			Chess960:    false,
+		},
+		{
+			Fen:         "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+			XFen:        "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w KQkq - 2 9",
+			ShredderFen: "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+			Chess960:    true,
+		},
+		{
+			Fen:         "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9",
+			XFen:        "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w kq - 0 9",
+			ShredderFen: "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9",
+			Chess960:    true,
+		},
+		{
+			Fen:         "1r2k1r1/8/8/8/8/8/8/R1R1K3 w Cgb - 0 1",
+			XFen:        "1r2k1r1/8/8/8/8/8/8/R1R1K3 w Ckq - 0 1",
+			ShredderFen: "1r2k1r1/8/8/8/8/8/8/R1R1K3 w Cgb - 0 1",
+			Chess960:    true,
+		},
+		{
+			Fen:         "1r2k1r1/8/8/8/8/8/8/R1R1K3 w Qkq - 0 1",
+			XFen:        "1r2k1r1/8/8/8/8/8/8/R1R1K3 w Qkq - 0 1",
+			ShredderFen: "1r2k1r1/8/8/8/8/8/8/R1R1K3 w Agb - 0 1",
+			Chess960:    true,
+		},
+	}
+
+	for _, c := range cases {
+		position, err := NewPosition(c.Fen)
+		if err != nil {
+			t.Fatalf("%s: fen %s returned error: %s", t.Name(), c.Fen, err)
+		}
+
+		if position.Fen() != c.XFen {
+			t.Fatalf("%s: expected %s got %s", t.Name(), c.XFen, position.Fen())
+		}
+
+		if position.ShredderFen() != c.ShredderFen {
+			t.Fatalf("%s: expected %s got %s", t.Name(), c.ShredderFen, position.ShredderFen())
+		}
+
+		if position.IsChess960() != c.Chess960 {
+			t.Fatalf("%s: expected chess960 to be %t for %s", t.Name(), c.Chess960, c.Fen)
+		}
+	}
+}
+
 func makeMoveTest(t *testing.T, position *Position, move string, fen string) {
 	err := position.MakeUciMove(move)
 	if err != nil {
@@ -169,6 +212,26 @@ func TestMakeUciMove(t *testing.T) {
 			StartingFen: "rnbqkbnr/ppp1p2P/8/8/3p4/8/PPPP1PPP/RNBQKBNR w KQkq - 0 5",
 			ExpectedFen: "rnbqkbBr/ppp1p3/8/8/3p4/8/PPPP1PPP/RNBQKBNR b KQkq - 0 5",
 		},
+		{
+			Move:        "e1g1",
+			StartingFen: "1r2k1r1/8/8/8/8/8/8/1R2K1R1 w KQkq - 0 1",
+			ExpectedFen: "1r2k1r1/8/8/8/8/8/8/1R3RK1 b kq - 1 1",
+		},
+		{
+			Move:        "e1b1",
+			StartingFen: "1r2k1r1/8/8/8/8/8/8/1R2K1R1 w KQkq - 0 1",
+			ExpectedFen: "1r2k1r1/8/8/8/8/8/8/2KR2R1 b kq - 1 1",
+		},
+		{
+			Move:        "g1h1",
+			StartingFen: "4k3/8/8/8/8/8/8/6KR w K - 0 1",
+			ExpectedFen: "4k3/8/8/8/8/8/8/5RK1 b - - 1 1",
+		},
+		{
+			Move:        "b8a8",
+			StartingFen: "rk6/8/8/8/8/8/8/4K3 b q - 0 1",
+			ExpectedFen: "2kr4/8/8/8/8/8/8/4K3 w - - 1 2",
+		},
 	}
 
 	for _, test := range tests {
@@ -228,6 +291,67 @@ func TestIsSquareAttacked(t *testing.T) {
 	squareAttackedTest(t, position, A8, false)
 }
 
+// forwardAttacks returns the squares the piece on the square attacks,
+// worked out from the piece rather than the square being attacked.
+func forwardAttacks(position Position, square Square) BitBoard {
+	piece, _ := position.GetPieceAt(square)
+	occupied := position.whiteBB | position.blackBB
+
+	switch piece.Type() {
+	case Pawn:
+		return pawnAttacks[sideIndex(piece.Color())][square]
+	case Knight:
+		return knightMoves[square]
+	case Bishop:
+		return getBishopAttacks(occupied, square)
+	case Rook:
+		return getRookAttacks(occupied, square)
+	case Queen:
+		return getBishopAttacks(occupied, square) | getRookAttacks(occupied, square)
+	case King:
+		return kingMoves[square]
+	}
+
+	return BitBoard(0)
+}
+
+func TestGetAttackers(t *testing.T) {
+	cases := []string{
+		StartingFen,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	}
+
+	for _, fen := range cases {
+		position, err := NewPosition(fen)
+		if err != nil {
+			t.Fatalf("%s: fen %s returned error: %s", t.Name(), fen, err)
+		}
+
+		expected := [64]BitBoard{}
+		for from := A1; from <= H8; from++ {
+			attacks := forwardAttacks(position, from)
+			for attacks > 0 {
+				expected[attacks.PopLsb()].SetBit(uint64(from))
+			}
+		}
+
+		for square := A1; square <= H8; square++ {
+			if position.GetAttackers(square) != expected[square] {
+				t.Fatalf("%s: expected attackers %d of %s got %d in %s", t.Name(), expected[square], square.ToAlgebraic(), position.GetAttackers(square), fen)
+			}
+
+			for _, color := range []Color{White, Black} {
+				attacked := expected[square]&position.GetColorBB(color) != 0
+				if position.IsSquareAttackedBy(square, color) != attacked {
+					t.Fatalf("%s: expected %s attacked by %s to be %v in %s", t.Name(), square.ToAlgebraic(), color, attacked, fen)
+				}
+			}
+		}
+	}
+}
+
 func kingInCheckTest(t *testing.T, fen string, color Color, expectedValue bool) {
 	position, err := NewPosition(fen)
 	if err != nil {
@@ -313,6 +437,8 @@ func TestIsCheckmated(t *testing.T) {
 	isCheckmatedTest(t, StartingFen, Black, false)
 
 	isCheckmatedTest(t, "r4k1q/2p2Q2/4p3/p4p2/PpP5/3P4/1P3PPP/4R1K1 b - - 2 31", Black, false)
+	isCheckmatedTest(t, "4R2k/6pp/8/4q3/8/8/8/6K1 b - - 0 1", Black, false) // the checking rook can be captured
+	isCheckmatedTest(t, "4R2k/6pp/8/8/1b6/8/8/6K1 b - - 0 1", Black, false) // the check can be blocked
 
 	isCheckmatedTest(t, "7k/6Q1/7P/5b2/3K4/8/2p5/2B5 b - - 8 57", Black, true)
 	isCheckmatedTest(t, "3k4/p2Q4/4Br2/1p6/8/3PK3/PPP5/R7 b - - 5 33", Black, true)
@@ -404,6 +530,14 @@ func TestUndo(t *testing.T) {
 			Fen:  "r1bqk1nr/ppppp2P/2n4b/8/8/8/PPPP1PPP/RNBQKBNR w KQkq - 1 5",
 			Move: "h7g8b",
 		},
+		{
+			Fen:  "1r2k1r1/8/8/8/8/8/8/1R2K1R1 b KQkq - 0 1",
+			Move: "e8b8",
+		},
+		{
+			Fen:  "4k3/8/8/8/8/8/8/5RK1 w Q - 0 1",
+			Move: "g1f1",
+		},
 	}
 
 	for _, c := range cases {
@@ -411,14 +545,167 @@ func TestUndo(t *testing.T) {
 	}
 }
 
+// undoTreeTest walks every move to the given depth checking that undoing a
+// move restores every part of the position, other than its history.
+func undoTreeTest(t *testing.T, position Position, depth int) {
+	if depth == 0 {
+		return
+	}
+
+	before := position
+	before.history, before.historyOwner = nil, nil
+
+	for _, move := range position.GenerateMoves(LegalMoveGeneration) {
+		position.MakeMove(move)
+		undoTreeTest(t, position, depth-1)
+		position.Undo()
+
+		after := position
+		after.history, after.historyOwner = nil, nil
+
+		if !reflect.DeepEqual(before, after) {
+			t.Fatalf("%s: expected undoing %s to restore %s got %s", t.Name(), move, before.Fen(), after.Fen())
+		}
+	}
+}
+
+func TestUndoTree(t *testing.T) {
+	cases := []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9",
+	}
+
+	for _, fen := range cases {
+		position, err := NewPosition(fen)
+		if err != nil {
+			t.Fatalf("%s: fen %s returned error: %s", t.Name(), fen, err)
+		}
+
+		undoTreeTest(t, position, 3)
+	}
+}
+
+func TestCopyHistory(t *testing.T) {
+	position, _ := NewPosition(StartingFen)
+	position.MakeUciMove("e2e4")
+
+	copy := position.Copy()
+	copy.MakeUciMove("e7e5")
+	position.MakeUciMove("c7c5")
+
+	copy.Undo()
+	position.Undo()
+
+	if copy.Fen() != position.Fen() {
+		t.Fatalf("%s: expected both positions to undo to %s got %s", t.Name(), position.Fen(), copy.Fen())
+	}
+
+	position.Undo()
+	if position.Fen() != StartingFen || !copy.CanUndo() {
+		t.Fatalf("%s: expected the copy to keep its own history got %s", t.Name(), position.Fen())
+	}
+}
+
+func TestValueCopyHistory(t *testing.T) {
+	position, _ := NewPosition(StartingFen)
+	position.MakeUciMove("e2e4")
+	afterE4 := position.Fen()
+
+	// both the copy and the position it was copied from make moves after the copy
+	branch := position
+	branch.MakeUciMove("e7e5")
+	position.MakeUciMove("c7c5")
+
+	branch.Undo()
+	position.Undo()
+	if branch.Fen() != afterE4 || position.Fen() != afterE4 {
+		t.Fatalf("%s: expected both positions to undo to %s got %s and %s", t.Name(), afterE4, branch.Fen(), position.Fen())
+	}
+
+	// the position takes back the move the copy was made after and plays another
+	branch = position
+	position.Undo()
+	position.MakeUciMove("d2d4")
+
+	branch.Undo()
+	if branch.Fen() != StartingFen {
+		t.Fatalf("%s: expected the copy to undo to %s got %s", t.Name(), StartingFen, branch.Fen())
+	}
+}
+
+func TestValueCopyMethods(t *testing.T) {
+	position, _ := NewPosition("r3k2r/8/8/8/4p3/8/3P4/R3K2R w KQkq - 0 1")
+
+	// methods that make moves on their own copy leave the history of other copies alone
+	moved := position
+	moved.MakeUciMove("d2d4")
+
+	castle, _ := position.ParseSan("O-O")
+	position.San(castle)
+	position.IsStalemate(Black)
+	position.IsCheckmated(Black)
+	position.GenerateMoves(LegalMoveGeneration)
+	moved.GenerateMoves(LegalMoveGeneration)
+
+	moved.Undo()
+	if moved.Fen() != position.Fen() {
+		t.Fatalf("%s: expected undoing to give %s got %s", t.Name(), position.Fen(), moved.Fen())
+	}
+}
+
 // Benchmarks
 
+// previousPosition keeps the copies made by undoBenchmark on the heap, the way
+// each position used to point to a copy of the one before it.
+var previousPosition *Position
+
+// undoBenchmark makes and takes back every legal move of the position, either
+// with the undo stack or by restoring a copy saved before the move.
+func undoBenchmark(b *testing.B, fen string, restore bool) {
+	position, _ := NewPosition(fen)
+	moves := position.GenerateMoves(LegalMoveGeneration)
+
+	// the position gets storage for its history before the copies are saved
+	position.MakeNullMove()
+	position.UndoInPlace()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, move := range moves {
+			if restore {
+				previousPosition = new(Position)
+				*previousPosition = position
+				position.MakeMove(move)
+				position = *previousPosition
+			} else {
+				position.MakeMove(move)
+				position.UndoInPlace()
+			}
+		}
+	}
+}
+
+func BenchmarkUndo(b *testing.B) {
+	kiwipete := "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+
+	b.Run("UndoRecord", func(b *testing.B) {
+		undoBenchmark(b, kiwipete, false)
+	})
+
+	b.Run("CopyRestore", func(b *testing.B) {
+		undoBenchmark(b, kiwipete, true)
+	})
+}
+
 func BenchmarkMakeUciMove(b *testing.B) {
 	position, _ := NewPosition(StartingFen)
 
 	for i := 0; i < b.N; i++ {
 		position.MakeUciMove("d2d4")
-		position.Undo()
+		position.UndoInPlace()
 	}
 }
 
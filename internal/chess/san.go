@@ -0,0 +1,271 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	kingsideCastleSan  = "O-O"
+	queensideCastleSan = "O-O-O"
+)
+
+// San returns the move in standard algebraic notation, such as Nf3, exd5,
+// O-O-O or e8=Q+. The move must be legal in the position.
+func (p Position) San(move Move) string {
+	var builder strings.Builder
+
+	if move.Type() == CastleMove {
+		if move.To() > move.From() {
+			builder.WriteString(kingsideCastleSan)
+		} else {
+			builder.WriteString(queensideCastleSan)
+		}
+	} else {
+		piece, _ := p.GetPieceAt(move.From())
+
+		if piece.Type() == Pawn {
+			// pawn captures are identified by the file the pawn came from
+			if move.IsCapture() {
+				builder.WriteByte(move.From().ToAlgebraic()[0])
+			}
+		} else {
+			builder.WriteRune(sanPieceCharacter(piece.Type()))
+			builder.WriteString(p.sanDisambiguation(move, piece.Type()))
+		}
+
+		if move.IsCapture() {
+			builder.WriteByte('x')
+		}
+
+		builder.WriteString(move.To().ToAlgebraic())
+
+		if move.IsPromotion() {
+			builder.WriteByte('=')
+			builder.WriteRune(sanPieceCharacter(move.PromotionPiece().Type()))
+		}
+	}
+
+	p.MakeMove(move)
+	if p.IsKingInCheck(p.turn) {
+		if len(p.GenerateMoves(LegalMoveGeneration)) == 0 {
+			builder.WriteByte('#')
+		} else {
+			builder.WriteByte('+')
+		}
+	}
+	p.Undo()
+
+	return builder.String()
+}
+
+// sanDisambiguation returns the file, rank or square of the moving piece
+// needed to tell the move apart from the moves of other pieces of the same
+// type going to the same square.
+func (p Position) sanDisambiguation(move Move, pieceType PieceType) string {
+	ambiguous := false
+	sameFile := false
+	sameRank := false
+
+	for _, other := range p.GenerateMoves(LegalMoveGeneration) {
+		if other.From() == move.From() || other.To() != move.To() || other.Type() == CastleMove {
+			continue
+		}
+
+		piece, _ := p.GetPieceAt(other.From())
+		if piece.Type() != pieceType {
+			continue
+		}
+
+		ambiguous = true
+		sameFile = sameFile || other.From().File() == move.From().File()
+		sameRank = sameRank || other.From().Rank() == move.From().Rank()
+	}
+
+	from := move.From().ToAlgebraic()
+
+	if !ambiguous {
+		return ""
+	} else if !sameFile {
+		return from[:1]
+	} else if !sameRank {
+		return from[1:]
+	}
+
+	return from
+}
+
+// ParseSan finds the legal move in the position described by the given move
+// in standard algebraic notation.
+//
+// Parsing is lenient, check and mate suffixes, annotations like ! and ?,
+// capture markers and the = before a promotion can all be left out or be
+// wrong. Castling can be written with O or 0 and the moving piece can be
+// given as a full square as in long algebraic notation.
+func (p Position) ParseSan(san string) (Move, error) {
+	text := strings.TrimSpace(san)
+	text = strings.TrimSuffix(text, "e.p.")
+	text = strings.TrimRight(text, "+#!? ")
+
+	if text == "" {
+		return NullMove, fmt.Errorf("%w: empty san move", ErrInvalidMove)
+	}
+
+	castle := strings.ToUpper(strings.ReplaceAll(text, "0", "O"))
+	if castle == kingsideCastleSan || castle == queensideCastleSan {
+		return p.parseSanCastle(san, castle == kingsideCastleSan)
+	}
+
+	pieceType := sanPieceType(rune(text[0]))
+	if pieceType != None {
+		text = text[1:]
+	} else {
+		pieceType = Pawn
+	}
+
+	promotion := None
+	if length := len(text); length > 0 {
+		if promotion = sanPieceType(rune(text[length-1])); promotion != None {
+			text = strings.TrimSuffix(text[:length-1], "=")
+		} else if length > 1 && text[length-2] == '=' {
+			// lowercase promotion pieces are only accepted after an = as they can't be told apart from files
+			promotion = sanPieceType(unicode.ToUpper(rune(text[length-1])))
+			if promotion == None {
+				return NullMove, fmt.Errorf("%w: invalid promotion piece in san move '%s'", ErrInvalidMove, san)
+			}
+
+			text = text[:length-2]
+		}
+	}
+
+	// capture markers and the dash of long algebraic notation aren't needed to find the move
+	text = strings.NewReplacer("x", "", "X", "", ":", "", "-", "").Replace(text)
+
+	if len(text) < 2 {
+		return NullMove, fmt.Errorf("%w: invalid san move '%s'", ErrInvalidMove, san)
+	}
+
+	destination := text[len(text)-2:]
+	if !isSanFile(destination[0]) {
+		return NullMove, fmt.Errorf("%w: invalid destination square in san move '%s'", ErrInvalidMove, san)
+	}
+
+	to, err := SquareFromAlgebraic(destination)
+	if err != nil || !to.IsValid() {
+		return NullMove, fmt.Errorf("%w: invalid destination square in san move '%s'", ErrInvalidMove, san)
+	}
+
+	fromFile := 0
+	fromRank := 0
+	for _, character := range text[:len(text)-2] {
+		if isSanFile(byte(character)) && fromFile == 0 {
+			fromFile = int(character-'a') + 1
+		} else if character >= '1' && character <= '8' && fromRank == 0 {
+			fromRank = int(character-'1') + 1
+		} else {
+			return NullMove, fmt.Errorf("%w: invalid san move '%s'", ErrInvalidMove, san)
+		}
+	}
+
+	matches := []Move{}
+	for _, move := range p.GenerateMoves(LegalMoveGeneration) {
+		if move.Type() == CastleMove || move.To() != to {
+			continue
+		}
+
+		piece, _ := p.GetPieceAt(move.From())
+		if piece.Type() != pieceType {
+			continue
+		}
+
+		if (fromFile != 0 && move.From().File() != fromFile) || (fromRank != 0 && move.From().Rank() != fromRank) {
+			continue
+		}
+
+		if move.IsPromotion() && move.PromotionPiece().Type() != promotion {
+			continue
+		}
+
+		matches = append(matches, move)
+	}
+
+	if len(matches) == 0 {
+		if pieceType == Pawn && promotion == None && (to.Rank() == 1 || to.Rank() == 8) {
+			return NullMove, fmt.Errorf("%w: san move '%s' is missing the piece to promote to", ErrInvalidMove, san)
+		}
+
+		return NullMove, fmt.Errorf("%w: san move '%s' is not legal in the position", ErrInvalidMove, san)
+	}
+
+	if len(matches) > 1 {
+		return NullMove, fmt.Errorf("%w: san move '%s' could be any of %s", ErrAmbiguousMove, san, p.sanList(matches))
+	}
+
+	if promotion != None && !matches[0].IsPromotion() {
+		return NullMove, fmt.Errorf("%w: san move '%s' is not a promotion", ErrInvalidMove, san)
+	}
+
+	return matches[0], nil
+}
+
+// parseSanCastle finds the legal castling move to the given side of the board.
+func (p Position) parseSanCastle(san string, kingside bool) (Move, error) {
+	for _, move := range p.GenerateMoves(LegalMoveGeneration) {
+		if move.Type() == CastleMove && (move.To() > move.From()) == kingside {
+			return move, nil
+		}
+	}
+
+	return NullMove, fmt.Errorf("%w: castling with '%s' is not legal in the position", ErrInvalidMove, san)
+}
+
+// MakeSanMove makes a move from the given move in standard algebraic notation.
+func (p *Position) MakeSanMove(san string) error {
+	move, err := p.ParseSan(san)
+	if err != nil {
+		return err
+	}
+
+	return p.MakeMove(move)
+}
+
+// sanList returns the moves in standard algebraic notation separated by commas.
+func (p Position) sanList(moves []Move) string {
+	sans := make([]string, len(moves))
+	for i, move := range moves {
+		sans[i] = p.San(move)
+	}
+
+	return strings.Join(sans, ", ")
+}
+
+// sanPieceCharacter returns the uppercase letter used for the piece type in
+// standard algebraic notation.
+func sanPieceCharacter(pieceType PieceType) rune {
+	return unicode.ToUpper(pieceType.Character())
+}
+
+// sanPieceType returns the piece type of an uppercase piece letter, or None
+// if the character isn't one.
+func sanPieceType(character rune) PieceType {
+	switch character {
+	case 'N':
+		return Knight
+	case 'B':
+		return Bishop
+	case 'R':
+		return Rook
+	case 'Q':
+		return Queen
+	case 'K':
+		return King
+	}
+
+	return None
+}
+
+// isSanFile returns whether the character is a file letter.
+func isSanFile(character byte) bool {
+	return character >= 'a' && character <= 'h'
+}
@@ -0,0 +1,249 @@
+package chess
+
+import (
+	"errors"
+	"testing"
+)
+
+func sanTest(t *testing.T, fen string, uci string, expectedSan string) {
+	position, err := NewPosition(fen)
+	if err != nil {
+		t.Fatalf("%s: fen %s returned error: %s", t.Name(), fen, err)
+	}
+
+	var move Move
+	found := false
+	for _, legalMove := range position.GenerateMoves(LegalMoveGeneration) {
+		if legalMove.Uci(position.IsChess960()) == uci {
+			move = legalMove
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatalf("%s: %s is not a legal move in %s", t.Name(), uci, fen)
+	}
+
+	san := position.San(move)
+	if san != expectedSan {
+		t.Fatalf("%s: expected san %s for %s got %s", t.Name(), expectedSan, uci, san)
+	}
+
+	parsed, err := position.ParseSan(san)
+	if err != nil {
+		t.Fatalf("%s: parsing %s returned error: %s", t.Name(), san, err)
+	}
+
+	if parsed != move {
+		t.Fatalf("%s: expected %s to be parsed as %s got %s", t.Name(), san, uci, parsed)
+	}
+}
+
+func TestSan(t *testing.T) {
+	cases := []struct {
+		Name string
+		Fen  string
+		Uci  string
+		San  string
+	}{
+		{
+			Name: "PawnPush",
+			Fen:  StartingFen,
+			Uci:  "e2e4",
+			San:  "e4",
+		},
+		{
+			Name: "KnightMove",
+			Fen:  StartingFen,
+			Uci:  "g1f3",
+			San:  "Nf3",
+		},
+		{
+			Name: "PawnCapture",
+			Fen:  "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+			Uci:  "e4d5",
+			San:  "exd5",
+		},
+		{
+			Name: "EnPassant",
+			Fen:  "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			Uci:  "e5f6",
+			San:  "exf6",
+		},
+		{
+			Name: "KingsideCastle",
+			Fen:  "rnbqk2r/pppp1ppp/5n2/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4",
+			Uci:  "e1g1",
+			San:  "O-O",
+		},
+		{
+			Name: "QueensideCastle",
+			Fen:  "r3kbnr/ppp1pppp/2nq4/3p1b2/3P1B2/2NQ4/PPP1PPPP/R3KBNR w KQkq - 6 5",
+			Uci:  "e1c1",
+			San:  "O-O-O",
+		},
+		{
+			Name: "Chess960Castle",
+			Fen:  "4k3/8/8/8/8/8/8/6KR w H - 0 1",
+			Uci:  "g1h1",
+			San:  "O-O",
+		},
+		{
+			Name: "Promotion",
+			Fen:  "8/4P3/8/8/8/8/k7/4K3 w - - 0 1",
+			Uci:  "e7e8q",
+			San:  "e8=Q",
+		},
+		{
+			Name: "UnderPromotionCapture",
+			Fen:  "3r4/4P3/8/8/8/8/k7/4K3 w - - 0 1",
+			Uci:  "e7d8n",
+			San:  "exd8=N",
+		},
+		{
+			Name: "Check",
+			Fen:  "4k3/8/8/8/8/8/8/R3K3 w - - 0 1",
+			Uci:  "a1a8",
+			San:  "Ra8+",
+		},
+		{
+			Name: "Checkmate",
+			Fen:  "6k1/5ppp/8/8/8/8/8/R3K3 w - - 0 1",
+			Uci:  "a1a8",
+			San:  "Ra8#",
+		},
+		{
+			Name: "DisambiguateByFile",
+			Fen:  "4k3/8/8/8/R6R/8/8/4K3 w - - 0 1",
+			Uci:  "a4d4",
+			San:  "Rad4",
+		},
+		{
+			Name: "DisambiguateByRank",
+			Fen:  "4k3/8/8/R7/8/8/8/R3K3 w - - 0 1",
+			Uci:  "a1a3",
+			San:  "R1a3",
+		},
+		{
+			Name: "DisambiguateBySquare",
+			Fen:  "k7/8/8/8/8/2Q1Q3/8/4Q1K1 w - - 0 1",
+			Uci:  "e3d2",
+			San:  "Qe3d2",
+		},
+		{
+			Name: "PinnedPieceNeedsNoDisambiguation",
+			Fen:  "4k3/8/8/8/b7/8/2N5/3K1N2 w - - 0 1",
+			Uci:  "f1e3",
+			San:  "Ne3",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			sanTest(t, c.Fen, c.Uci, c.San)
+		})
+	}
+}
+
+func parseSanTest(t *testing.T, fen string, san string, expectedUci string) {
+	position, err := NewPosition(fen)
+	if err != nil {
+		t.Fatalf("%s: fen %s returned error: %s", t.Name(), fen, err)
+	}
+
+	move, err := position.ParseSan(san)
+	if err != nil {
+		t.Fatalf("%s: parsing %s returned error: %s", t.Name(), san, err)
+	}
+
+	if move.String() != expectedUci {
+		t.Fatalf("%s: expected %s to be parsed as %s got %s", t.Name(), san, expectedUci, move)
+	}
+}
+
+func TestParseSanLenient(t *testing.T) {
+	cases := []struct {
+		Fen string
+		San string
+		Uci string
+	}{
+		{Fen: StartingFen, San: "Nf3!?", Uci: "g1f3"},
+		{Fen: StartingFen, San: " e4 ", Uci: "e2e4"},
+		{Fen: StartingFen, San: "Ng1-f3", Uci: "g1f3"},
+		{Fen: StartingFen, San: "e2e4", Uci: "e2e4"},
+		{Fen: "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2", San: "ed5", Uci: "e4d5"},
+		{Fen: "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", San: "exf6 e.p.", Uci: "e5f6"},
+		{Fen: "rnbqk2r/pppp1ppp/5n2/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4", San: "0-0", Uci: "e1g1"},
+		{Fen: "r3kbnr/ppp1pppp/2nq4/3p1b2/3P1B2/2NQ4/PPP1PPPP/R3KBNR w KQkq - 6 5", San: "o-o-o+", Uci: "e1c1"},
+		{Fen: "8/4P3/8/8/8/8/k7/4K3 w - - 0 1", San: "e8Q", Uci: "e7e8q"},
+		{Fen: "8/4P3/8/8/8/8/k7/4K3 w - - 0 1", San: "e8=r", Uci: "e7e8r"},
+		{Fen: "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", San: "Ra8", Uci: "a1a8"},
+	}
+
+	for _, c := range cases {
+		parseSanTest(t, c.Fen, c.San, c.Uci)
+	}
+}
+
+func TestParseSanErrors(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Fen      string
+		San      string
+		Expected error
+	}{
+		{
+			Name:     "Ambiguous",
+			Fen:      "4k3/8/8/8/R6R/8/8/4K3 w - - 0 1",
+			San:      "Rd4",
+			Expected: ErrAmbiguousMove,
+		},
+		{
+			Name:     "Illegal",
+			Fen:      StartingFen,
+			San:      "e5",
+			Expected: ErrInvalidMove,
+		},
+		{
+			Name:     "Pinned",
+			Fen:      "4k3/8/8/8/b7/8/2N5/3K4 w - - 0 1",
+			San:      "Ne3",
+			Expected: ErrInvalidMove,
+		},
+		{
+			Name:     "MissingPromotion",
+			Fen:      "8/4P3/8/8/8/8/k7/4K3 w - - 0 1",
+			San:      "e8",
+			Expected: ErrInvalidMove,
+		},
+		{
+			Name:     "CastlingNotAllowed",
+			Fen:      StartingFen,
+			San:      "O-O",
+			Expected: ErrInvalidMove,
+		},
+		{
+			Name:     "Garbage",
+			Fen:      StartingFen,
+			San:      "Zz9",
+			Expected: ErrInvalidMove,
+		},
+		{
+			Name:     "Empty",
+			Fen:      StartingFen,
+			San:      "+",
+			Expected: ErrInvalidMove,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			position, _ := NewPosition(c.Fen)
+
+			_, err := position.ParseSan(c.San)
+			if !errors.Is(err, c.Expected) {
+				t.Fatalf("%s: expected error %v for %s got %v", t.Name(), c.Expected, c.San, err)
+			}
+		})
+	}
+}
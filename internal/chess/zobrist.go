@@ -3,13 +3,19 @@ package chess
 import "math/rand"
 
 const (
-	numSquares    = 64
-	numPieceTypes = 6
-	numSides      = 2
+	numSquares        = 64
+	numPieceTypes     = 6
+	numSides          = 2
+	numCastlingRights = 16
+	numFiles          = 8
 )
 
 var zobristTable [numSquares][numPieceTypes][numSides]uint64
 
+var zobristSide uint64                              // Hashed in when it is black's turn.
+var zobristCastlingRights [numCastlingRights]uint64 // Indexed by every combination of castling rights.
+var zobristEnPassant [numFiles]uint64               // Indexed by the file of the en passant square.
+
 func init() {
 	for i := 0; i < numSquares; i++ {
 		for j := 0; j < numPieceTypes; j++ {
@@ -18,8 +24,71 @@ func init() {
 			}
 		}
 	}
+
+	zobristSide = rand.Uint64()
+
+	// no castling rights hashes to zero so it doesn't need to be special cased
+	for i := 1; i < numCastlingRights; i++ {
+		zobristCastlingRights[i] = rand.Uint64()
+	}
+
+	for i := 0; i < numFiles; i++ {
+		zobristEnPassant[i] = rand.Uint64()
+	}
 }
 
+// pieceHash returns the zobrist key for the piece being on the square.
+func pieceHash(piece Piece, square Square) uint64 {
+	var pieceIndex int
+	switch piece.Type() {
+	case Pawn:
+		pieceIndex = 0
+		break
+	case Knight:
+		pieceIndex = 1
+		break
+	case Bishop:
+		pieceIndex = 2
+		break
+	case Rook:
+		pieceIndex = 3
+		break
+	case Queen:
+		pieceIndex = 4
+		break
+	case King:
+		pieceIndex = 5
+		break
+	}
+
+	var colorIndex = 0
+	if piece.Color() == Black {
+		colorIndex = 1
+	}
+
+	return zobristTable[square][pieceIndex][colorIndex]
+}
+
+// stateHash returns the zobrist key for everything in the position other
+// than the pieces, which is the side to move, castling rights and en passant
+// square.
+func stateHash(p *Position) uint64 {
+	hash := zobristCastlingRights[p.castlingRights]
+
+	if p.turn == Black {
+		hash ^= zobristSide
+	}
+
+	if p.EnPassantPossible() {
+		hash ^= zobristEnPassant[p.enPassant.File()-1]
+	}
+
+	return hash
+}
+
+// generateHash calculates the zobrist hash of the position from scratch. The
+// hash is kept up to date as moves are made so this is only needed when
+// creating a position.
 func generateHash(p Position) uint64 {
 	var hash uint64 = 0
 
@@ -28,37 +97,10 @@ func generateHash(p Position) uint64 {
 			square := SquareFromRankFile(rank+1, file+1)
 			piece, err := p.GetPieceAt(square)
 			if err == nil {
-				var pieceIndex int
-				switch piece.Type() {
-				case Pawn:
-					pieceIndex = 0
-					break
-				case Knight:
-					pieceIndex = 1
-					break
-				case Bishop:
-					pieceIndex = 2
-					break
-				case Rook:
-					pieceIndex = 3
-					break
-				case Queen:
-					pieceIndex = 4
-					break
-				case King:
-					pieceIndex = 5
-					break
-				}
-
-				var colorIndex = 0
-				if piece.Color() == Black {
-					colorIndex = 1
-				}
-
-				hash ^= zobristTable[square][pieceIndex][colorIndex]
+				hash ^= pieceHash(piece, square)
 			}
 		}
 	}
 
-	return hash
+	return hash ^ stateHash(&p)
 }
@@ -21,3 +21,113 @@ func BenchmarkGenerateHash(b *testing.B) {
 		})
 	}
 }
+
+// hashTreeTest walks every move to the given depth checking that the
+// incrementally updated hash matches the hash calculated from scratch,
+// including after null moves and undoing moves.
+func hashTreeTest(t *testing.T, position Position, depth int) {
+	if position.Hash() != generateHash(position) {
+		t.Fatalf("%s: expected hash %x got %x for %s", t.Name(), generateHash(position), position.Hash(), position.Fen())
+	}
+
+	if depth == 0 {
+		return
+	}
+
+	hash := position.Hash()
+
+	if !position.IsKingInCheck(position.Turn()) {
+		position.MakeNullMove()
+		if position.Hash() != generateHash(position) {
+			t.Fatalf("%s: expected hash %x after a null move got %x for %s", t.Name(), generateHash(position), position.Hash(), position.Fen())
+		}
+
+		position.Undo()
+	}
+
+	for _, move := range position.GenerateMoves(LegalMoveGeneration) {
+		position.MakeMove(move)
+		hashTreeTest(t, position, depth-1)
+		position.Undo()
+
+		if position.Hash() != hash {
+			t.Fatalf("%s: expected hash %x after undoing %s got %x", t.Name(), hash, move, position.Hash())
+		}
+	}
+}
+
+func TestIncrementalHash(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Fen   string
+		Depth int
+	}{
+		{Name: "StartingPosition", Fen: StartingFen, Depth: 3},
+		{Name: "Kiwipete", Fen: "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", Depth: 3},
+		{Name: "EnPassant", Fen: "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", Depth: 4},
+		{Name: "Promotions", Fen: "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", Depth: 3},
+		{Name: "Chess960", Fen: "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", Depth: 3},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			position, err := NewPosition(c.Fen)
+			if err != nil {
+				t.Fatalf("%s: fen %s returned error: %s", t.Name(), c.Fen, err)
+			}
+
+			hashTreeTest(t, position, c.Depth)
+		})
+	}
+}
+
+func TestHashState(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Fen   string
+		Other string
+	}{
+		{
+			Name:  "Turn",
+			Fen:   "4k3/8/8/8/8/8/8/4K3 w - - 0 1",
+			Other: "4k3/8/8/8/8/8/8/4K3 b - - 0 1",
+		},
+		{
+			Name:  "CastlingRights",
+			Fen:   "r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1",
+			Other: "r3k2r/8/8/8/8/8/8/R3K2R w Kkq - 0 1",
+		},
+		{
+			Name:  "EnPassant",
+			Fen:   "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1",
+			Other: "4k3/8/8/3pP3/8/8/8/4K3 w - - 0 1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			position, _ := NewPosition(c.Fen)
+			other, _ := NewPosition(c.Other)
+
+			if position.Hash() == other.Hash() {
+				t.Fatalf("%s: expected %s and %s to have different hashes", t.Name(), c.Fen, c.Other)
+			}
+		})
+	}
+}
+
+func TestHashTransposition(t *testing.T) {
+	position, _ := NewPosition(StartingFen)
+	for _, uci := range []string{"g1f3", "g8f6", "b1c3", "b8c6"} {
+		position.MakeUciMove(uci)
+	}
+
+	transposed, _ := NewPosition(StartingFen)
+	for _, uci := range []string{"b1c3", "b8c6", "g1f3", "g8f6"} {
+		transposed.MakeUciMove(uci)
+	}
+
+	if position.Hash() != transposed.Hash() {
+		t.Fatalf("%s: expected transposed moves to reach the same hash got %x and %x", t.Name(), position.Hash(), transposed.Hash())
+	}
+}
@@ -8,6 +8,8 @@ import (
 const (
 	DrawScore int = 0
 	MateScore int = 50000
+
+	MaxMatePly int = 64 // Mates further than this many plies away are not told apart from normal scores.
 )
 
 // Bonuses
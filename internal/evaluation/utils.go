@@ -9,3 +9,8 @@ func evaluationMultiplier(color chess.Color) int {
 
 	return -1
 }
+
+// IsMateScore returns whether the score is the result of a forced mate.
+func IsMateScore(score int) bool {
+	return score >= MateScore-MaxMatePly || score <= -MateScore+MaxMatePly
+}
@@ -0,0 +1,47 @@
+package evaluation
+
+import "math"
+
+// Coefficients of the polynomials used to determine the parameters of the
+// win rate model from the game ply. These are fitted from engine self play
+// games and are the same as the ones used by Stockfish 15.1.
+var (
+	winRateA = [4]float64{0.50379905, -4.12755858, 18.95487051, 152.00733652}
+	winRateB = [4]float64{-1.71790378, 10.71543602, -17.05515898, 41.15680404}
+)
+
+const (
+	maxWdlPly   = 240  // Plies past this point are treated as this ply.
+	maxWdlScore = 2000 // Scores are clamped to this value in centipawns.
+)
+
+// winRate returns the chance in permille that the player with the given
+// score wins the game.
+func winRate(score int, ply int) int {
+	m := float64(min(max(ply, 0), maxWdlPly)) / 64
+
+	a := ((winRateA[0]*m+winRateA[1])*m+winRateA[2])*m + winRateA[3]
+	b := ((winRateB[0]*m+winRateB[1])*m+winRateB[2])*m + winRateB[3]
+
+	x := float64(min(max(score, -maxWdlScore), maxWdlScore))
+
+	return int(0.5 + 1000/(1+math.Exp((a-x)/b)))
+}
+
+// WinDrawLoss returns the chance in permille of winning, drawing and losing
+// the game for the player with the given centipawn score at the given ply.
+func WinDrawLoss(score int, ply int) (int, int, int) {
+	if IsMateScore(score) {
+		if score > 0 {
+			return 1000, 0, 0
+		}
+
+		return 0, 0, 1000
+	}
+
+	win := winRate(score, ply)
+	loss := winRate(-score, ply)
+	draw := 1000 - win - loss
+
+	return win, draw, loss
+}
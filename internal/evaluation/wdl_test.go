@@ -0,0 +1,75 @@
+package evaluation
+
+import "testing"
+
+func TestWinDrawLoss(t *testing.T) {
+	cases := []struct {
+		Score int
+		Ply   int
+	}{
+		{Score: 0, Ply: 0},
+		{Score: 50, Ply: 20},
+		{Score: -300, Ply: 60},
+		{Score: 1500, Ply: 100},
+		{Score: 100000, Ply: 300},
+	}
+
+	for _, c := range cases {
+		win, draw, loss := WinDrawLoss(c.Score, c.Ply)
+		if win+draw+loss != 1000 {
+			t.Fatalf("%s: expected wdl for score %d to add up to 1000 got %d %d %d", t.Name(), c.Score, win, draw, loss)
+		}
+
+		if win < 0 || draw < 0 || loss < 0 {
+			t.Fatalf("%s: expected wdl for score %d to not be negative got %d %d %d", t.Name(), c.Score, win, draw, loss)
+		}
+
+		// the model is symmetric so the opponent's win is our loss
+		opponentWin, opponentDraw, opponentLoss := WinDrawLoss(-c.Score, c.Ply)
+		if opponentWin != loss || opponentDraw != draw || opponentLoss != win {
+			t.Fatalf("%s: expected wdl for score %d to mirror wdl for score %d", t.Name(), c.Score, -c.Score)
+		}
+	}
+}
+
+func TestWinDrawLossIncreasesWithScore(t *testing.T) {
+	previousWin, _, previousLoss := WinDrawLoss(-1000, 40)
+	for score := -900; score <= 1000; score += 100 {
+		win, _, loss := WinDrawLoss(score, 40)
+		if win < previousWin || loss > previousLoss {
+			t.Fatalf("%s: expected a higher score %d to not decrease the chance of winning", t.Name(), score)
+		}
+
+		previousWin = win
+		previousLoss = loss
+	}
+}
+
+func TestWinDrawLossMate(t *testing.T) {
+	win, draw, loss := WinDrawLoss(MateScore-3, 50)
+	if win != 1000 || draw != 0 || loss != 0 {
+		t.Fatalf("%s: expected a forced mate to be a certain win got %d %d %d", t.Name(), win, draw, loss)
+	}
+
+	win, draw, loss = WinDrawLoss(-MateScore+4, 50)
+	if win != 0 || draw != 0 || loss != 1000 {
+		t.Fatalf("%s: expected getting mated to be a certain loss got %d %d %d", t.Name(), win, draw, loss)
+	}
+}
+
+func TestIsMateScore(t *testing.T) {
+	scores := map[int]bool{
+		MateScore - 1:               true,
+		MateScore - MaxMatePly:      true,
+		MateScore - MaxMatePly - 1:  false,
+		-MateScore + MaxMatePly:     true,
+		-MateScore + MaxMatePly + 1: false,
+		0:                           false,
+	}
+
+	for score, expected := range scores {
+		if IsMateScore(score) != expected {
+			t.Fatalf("%s: expected score %d to have a mate status of %v", t.Name(), score, expected)
+		}
+	}
+}
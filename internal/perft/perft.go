@@ -5,7 +5,13 @@ import (
 	"rosaline/internal/chess"
 )
 
+// Perft counts the leaf nodes of the move tree of the position to the given
+// depth, printing the count for each move of the position if print is set.
 func Perft(position chess.Position, depth int, print bool) uint64 {
+	return perft(&position, depth, print)
+}
+
+func perft(position *chess.Position, depth int, print bool) uint64 {
 	if depth == 0 {
 		return 1
 	}
@@ -13,8 +19,15 @@ func Perft(position chess.Position, depth int, print bool) uint64 {
 	leaf := depth == 2
 
 	var nodes uint64 = 0
-	moves := position.GenerateMoves(chess.LegalMoveGeneration)
-	for _, move := range moves {
+
+	var moves chess.MoveList
+	position.GenerateMoveList(chess.LegalMoveGeneration, &moves)
+
+	// the replies are only generated to be counted so the same list is reused for each move
+	var replies chess.MoveList
+
+	for i := 0; i < moves.Len(); i++ {
+		move := moves.Move(i)
 		err := position.MakeMove(move)
 		if err != nil {
 			panic(err)
@@ -22,18 +35,19 @@ func Perft(position chess.Position, depth int, print bool) uint64 {
 
 		var count uint64
 		if leaf {
-			count = uint64(len(position.GenerateMoves(chess.LegalMoveGeneration)))
+			position.GenerateMoveList(chess.LegalMoveGeneration, &replies)
+			count = uint64(replies.Len())
 		} else {
-			count = Perft(position, depth-1, false)
+			count = perft(position, depth-1, false)
 		}
 
 		nodes += count
 
 		if print {
-			fmt.Printf("%s: %d\n", move, count)
+			fmt.Printf("%s: %d\n", move.Uci(position.IsChess960()), count)
 		}
 
-		position.Undo()
+		position.UndoInPlace()
 	}
 
 	return nodes
@@ -23,3 +23,127 @@ func TestPerft(t *testing.T) {
 	perftTest(t, position, 3, 8902)
 	perftTest(t, position, 4, 197281)
 }
+
+func TestPerftPositions(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Fen   string
+		Nodes []uint64
+	}{
+		{
+			Name:  "Kiwipete",
+			Fen:   "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			Nodes: []uint64{48, 2039, 97862, 4085603},
+		},
+		{
+			Name:  "EndgameEnPassant",
+			Fen:   "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+			Nodes: []uint64{14, 191, 2812, 43238},
+		},
+		{
+			Name:  "Promotions",
+			Fen:   "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+			Nodes: []uint64{6, 264, 9467},
+		},
+		{
+			Name:  "CastlingThroughCheck",
+			Fen:   "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+			Nodes: []uint64{44, 1486, 62379},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			position, err := chess.NewPosition(c.Fen)
+			if err != nil {
+				t.Fatalf("%s: error occured creating position for fen: %s", t.Name(), c.Fen)
+			}
+
+			for depth, nodes := range c.Nodes {
+				perftTest(t, position, depth+1, nodes)
+			}
+		})
+	}
+}
+
+func TestPerftChess960(t *testing.T) {
+	cases := []struct {
+		Fen   string
+		Nodes []uint64
+	}{
+		{
+			Fen:   "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+			Nodes: []uint64{21, 528, 12189},
+		},
+		{
+			Fen:   "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9",
+			Nodes: []uint64{21, 807, 18002},
+		},
+		{
+			Fen:   "b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9",
+			Nodes: []uint64{20, 479, 10471},
+		},
+		{
+			Fen:   "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9",
+			Nodes: []uint64{22, 593, 13440},
+		},
+		{
+			Fen:   "1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9",
+			Nodes: []uint64{28, 1120, 31058},
+		},
+		{
+			Fen:   "qnbnr1kr/ppp1b1pp/4p3/3p1p2/8/2NPP3/PPP1BPPP/QNB1R1KR w HEhe - 1 9",
+			Nodes: []uint64{29, 899, 26578},
+		},
+	}
+
+	for _, c := range cases {
+		position, err := chess.NewPosition(c.Fen)
+		if err != nil {
+			t.Fatalf("%s: error occured creating position for fen: %s", t.Name(), c.Fen)
+		}
+
+		for depth, nodes := range c.Nodes {
+			perftTest(t, position, depth+1, nodes)
+		}
+	}
+}
+
+func TestPerftSuite(t *testing.T) {
+	suite := []string{
+		"4k3/8/8/8/8/8/8/4K2R w K - ;D1 15 ;D2 66 ;D3 1197",
+		"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - ;D1 26 ;D2 568 ;D3 13744",
+		"8/8/8/8/8/8/6k1/4K2R b K - ;D1 3 ;D2 32 ;D3 134 ;D4 2073",
+		"8/Pk6/8/8/8/8/6Kp/8 w - - ;D1 11 ;D2 97 ;D3 887",
+	}
+
+	for _, line := range suite {
+		epd, err := chess.NewEPD(line)
+		if err != nil {
+			t.Fatalf("%s: error occured parsing epd: %s", t.Name(), line)
+		}
+
+		for _, depth := range epd.PerftDepths() {
+			nodes, _ := epd.PerftNodes(depth)
+			perftTest(t, epd.Position, depth, nodes)
+		}
+	}
+}
+
+func BenchmarkPerft(b *testing.B) {
+	cases := []string{
+		chess.StartingFen,
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	}
+
+	for _, c := range cases {
+		b.Run(c, func(b *testing.B) {
+			position, _ := chess.NewPosition(c)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Perft(position, 3, false)
+			}
+		})
+	}
+}
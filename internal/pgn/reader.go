@@ -0,0 +1,390 @@
+package pgn
+
+import (
+	"io"
+	"math"
+	"regexp"
+	"rosaline/internal/chess"
+	"strconv"
+	"strings"
+)
+
+const (
+	WhiteWinsResult = "1-0"
+	BlackWinsResult = "0-1"
+	DrawResult      = "1/2-1/2"
+	UnknownResult   = "*"
+)
+
+// isResult returns whether the symbol is a game termination marker.
+func isResult(symbol string) bool {
+	switch symbol {
+	case WhiteWinsResult, BlackWinsResult, DrawResult, UnknownResult:
+		return true
+	}
+
+	return false
+}
+
+// Reader reads games from pgn text one at a time, so large files never have
+// to be loaded into memory all at once.
+type Reader struct {
+	scanner *scanner
+}
+
+// NewReader creates a Reader that reads games from the given reader.
+func NewReader(reader io.Reader) *Reader {
+	return &Reader{
+		scanner: newScanner(reader),
+	}
+}
+
+// ReadAll reads every game from the given reader.
+func ReadAll(reader io.Reader) ([]chess.Game, error) {
+	games := []chess.Game{}
+
+	pgnReader := NewReader(reader)
+	for {
+		game, err := pgnReader.Next()
+		if err == io.EOF {
+			return games, nil
+		} else if err != nil {
+			return games, err
+		}
+
+		games = append(games, game)
+	}
+}
+
+// Next reads the next game, returning io.EOF once there are no more games.
+//
+// Problems with the pgn are returned as a *SyntaxError, including moves
+// that aren't legal. Reading can't continue after an error.
+func (r *Reader) Next() (chess.Game, error) {
+	// comments between games don't belong to either game
+	err := r.skipComments()
+	if err != nil {
+		return chess.Game{}, err
+	}
+
+	t, err := r.scanner.peek()
+	if err != nil {
+		return chess.Game{}, err
+	}
+
+	if t.tokenType == eofToken {
+		return chess.Game{}, io.EOF
+	}
+
+	tags, comment, err := r.readTags()
+	if err != nil {
+		return chess.Game{}, err
+	}
+
+	game, err := newGame(tags)
+	if err != nil {
+		return chess.Game{}, r.scanner.errorAt(t, "%s", err)
+	}
+
+	moves, result, err := r.readMovetext(game.Position, comment, 0)
+	if err != nil {
+		return chess.Game{}, err
+	}
+
+	for _, move := range moves {
+		// the moves have already been checked to be legal
+		game.MakeMove(move.Move)
+		*game.LastMove() = move
+	}
+
+	game.SetTag("Result", result)
+	setResult(&game, result)
+
+	return game, nil
+}
+
+func (r *Reader) skipComments() error {
+	for {
+		t, err := r.scanner.peek()
+		if err != nil {
+			return err
+		}
+
+		if t.tokenType != commentToken {
+			return nil
+		}
+
+		r.scanner.next()
+	}
+}
+
+// readTags reads the tag pairs at the start of a game along with any
+// comment after them, which describes the starting position.
+func (r *Reader) readTags() ([]chess.Tag, string, error) {
+	tags := []chess.Tag{}
+	comment := ""
+
+	for {
+		t, err := r.scanner.peek()
+		if err != nil {
+			return tags, comment, err
+		}
+
+		if t.tokenType == commentToken {
+			r.scanner.next()
+			comment = joinComments(comment, strings.TrimSpace(t.value))
+			continue
+		}
+
+		if t.tokenType != openBracketToken {
+			return tags, comment, nil
+		}
+
+		r.scanner.next()
+		comment = ""
+
+		name, err := r.expect(symbolToken, "tag name")
+		if err != nil {
+			return tags, comment, err
+		}
+
+		value, err := r.expect(stringToken, "tag value")
+		if err != nil {
+			return tags, comment, err
+		}
+
+		_, err = r.expect(closeBracketToken, "']' to close the tag")
+		if err != nil {
+			return tags, comment, err
+		}
+
+		tags = append(tags, chess.Tag{Name: name.value, Value: value.value})
+	}
+}
+
+// expect reads the next token, returning an error if it isn't of the given type.
+func (r *Reader) expect(tokenType tokenType, description string) (token, error) {
+	t, err := r.scanner.next()
+	if err != nil {
+		return t, err
+	}
+
+	if t.tokenType != tokenType {
+		return t, r.scanner.errorAt(t, "expected %s but found %s", description, describe(t))
+	}
+
+	return t, nil
+}
+
+// describe returns how the token is shown in error messages.
+func describe(t token) string {
+	if t.tokenType == symbolToken {
+		return "'" + t.value + "'"
+	}
+
+	return t.tokenType.String()
+}
+
+// newGame creates the game described by the tags, starting from the
+// position in the FEN tag if there is one.
+func newGame(tags []chess.Tag) (chess.Game, error) {
+	fen := chess.StartingFen
+	chess960 := false
+
+	for _, tag := range tags {
+		switch tag.Name {
+		case "FEN":
+			fen = tag.Value
+			break
+		case "Variant":
+			variant := strings.ToLower(tag.Value)
+			chess960 = variant == "chess960" || variant == "chess 960" || variant == "fischerandom"
+			break
+		}
+	}
+
+	game, err := chess.NewGame(fen)
+	if err != nil {
+		return game, err
+	}
+
+	if chess960 {
+		game.Position.SetChess960(true)
+	}
+
+	for _, tag := range tags {
+		game.SetTag(tag.Name, tag.Value)
+	}
+
+	return game, nil
+}
+
+// readMovetext reads the moves played from the given position up to the
+// result of the game or, inside a variation, the end of the variation.
+//
+// The comment is about the starting position and is added to the first move.
+func (r *Reader) readMovetext(position chess.Position, comment string, depth int) ([]chess.GameMove, string, error) {
+	moves := []chess.GameMove{}
+	before := position
+
+	// comments before any moves describe the position before the first move
+	pendingComment := comment
+
+	for {
+		t, err := r.scanner.next()
+		if err != nil {
+			return moves, "", err
+		}
+
+		var last *chess.GameMove
+		if len(moves) > 0 {
+			last = &moves[len(moves)-1]
+		}
+
+		switch t.tokenType {
+		case eofToken:
+			if depth > 0 {
+				return moves, "", r.scanner.errorAt(t, "variation is never closed")
+			}
+
+			return moves, "", r.scanner.errorAt(t, "game is missing a result")
+		case periodToken:
+			break
+		case commentToken:
+			text := strings.TrimSpace(t.value)
+			if last == nil || pendingComment != "" {
+				pendingComment = joinComments(pendingComment, text)
+			} else {
+				evaluation, rest := extractEvaluation(text)
+				if evaluation != nil {
+					last.Evaluation = evaluation
+				}
+
+				last.Comment = joinComments(last.Comment, rest)
+			}
+			break
+		case nagToken:
+			if last == nil {
+				return moves, "", r.scanner.errorAt(t, "annotation before any move")
+			}
+
+			last.Nags = append(last.Nags, t.nag)
+			break
+		case openParenthesisToken:
+			if last == nil {
+				return moves, "", r.scanner.errorAt(t, "variation before any move")
+			}
+
+			variation, _, err := r.readMovetext(before, "", depth+1)
+			if err != nil {
+				return moves, "", err
+			}
+
+			last.Variations = append(last.Variations, variation)
+			break
+		case closeParenthesisToken:
+			if depth == 0 {
+				return moves, "", r.scanner.errorAt(t, "')' without a variation to close")
+			}
+
+			return moves, "", nil
+		case symbolToken:
+			if isResult(t.value) {
+				if depth > 0 {
+					return moves, "", r.scanner.errorAt(t, "result %s inside a variation", t.value)
+				}
+
+				return moves, t.value, nil
+			}
+
+			// move numbers are only there for people reading the pgn
+			if isMoveNumber(t.value) {
+				break
+			}
+
+			move, err := position.ParseSan(t.value)
+			if err != nil {
+				return moves, "", r.scanner.errorAt(t, "%s", err)
+			}
+
+			before = position
+			position.MakeMove(move)
+
+			moves = append(moves, chess.GameMove{Move: move, CommentBefore: pendingComment})
+			pendingComment = ""
+			break
+		case openBracketToken:
+			return moves, "", r.scanner.errorAt(t, "game is missing a result before the next game's tags")
+		default:
+			return moves, "", r.scanner.errorAt(t, "unexpected %s in movetext", describe(t))
+		}
+	}
+}
+
+// isMoveNumber returns whether the symbol is made up only of digits.
+func isMoveNumber(symbol string) bool {
+	return strings.Trim(symbol, "0123456789") == ""
+}
+
+// evaluationPattern matches the [%eval] command guis embed in comments.
+var evaluationPattern = regexp.MustCompile(`\[%eval\s+(#?)([-+]?[0-9.]+)[^\]]*\]`)
+
+// extractEvaluation returns the evaluation in the comment, if it has one,
+// along with the rest of the comment.
+func extractEvaluation(text string) (*chess.Evaluation, string) {
+	match := evaluationPattern.FindStringSubmatchIndex(text)
+	if match == nil {
+		return nil, text
+	}
+
+	value := text[match[4]:match[5]]
+	rest := strings.TrimSpace(text[:match[0]] + text[match[1]:])
+
+	if match[3] > match[2] {
+		moves, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, text
+		}
+
+		return &chess.Evaluation{Score: moves, Mate: true}, rest
+	}
+
+	pawns, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, text
+	}
+
+	return &chess.Evaluation{Score: int(math.Round(pawns * 100))}, rest
+}
+
+// joinComments joins two comments on the same move.
+func joinComments(first string, second string) string {
+	if first == "" {
+		return second
+	} else if second == "" {
+		return first
+	}
+
+	return first + " " + second
+}
+
+// setResult ends the game with the given result when the moves alone
+// didn't end it. As pgn doesn't say how a game was won the loser is
+// treated as having resigned.
+func setResult(game *chess.Game, result string) {
+	if game.Status() != chess.InProgress {
+		return
+	}
+
+	switch result {
+	case WhiteWinsResult:
+		game.Resign(chess.Black)
+		break
+	case BlackWinsResult:
+		game.Resign(chess.White)
+		break
+	case DrawResult:
+		game.AcceptDraw()
+		break
+	}
+}
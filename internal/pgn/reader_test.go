@@ -0,0 +1,192 @@
+package pgn
+
+import (
+	"errors"
+	"io"
+	"rosaline/internal/chess"
+	"strings"
+	"testing"
+)
+
+const testPgn = `[Event "Casual Game"]
+[Site "Berlin GER"]
+[Date "1852.??.??"]
+[Round "?"]
+[White "Adolf Anderssen"]
+[Black "Jean Dufresne"]
+[Result "1-0"]
+[ECO "C52"]
+
+{The Evergreen Game} 1.e4 e5 2.Nf3 Nc6 3.Bc4 Bc5 4.b4 Bxb4 5.c3 Ba5 6.d4 exd4 7.O-O
+d3 8.Qb3 Qf6 9.e5 Qg6 10.Re1 Nge7 11.Ba3 b5 12.Qxb5 Rb8 13.Qa4 Bb6 14.Nbd2 Bb7
+15.Ne4 Qf5 16.Bxd3 Qh5 17.Nf6+ gxf6 18.exf6 Rg8 19.Rad1 Qxf3 20.Rxe7+ Nxe7
+21.Qxd7+ Kxd7 22.Bf5+ Ke8 23.Bd7+ Kf8 24.Bxe7# 1-0
+
+[Event "Annotated"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+1. e4 $1 {best by test} e5 (1... c5 {the sicilian} 2. Nf3 (2. c3) d6) 2. Nf3!? ; a line comment
+Nc6 *
+
+[FEN "4k3/8/8/8/8/8/8/R3K3 w - - 0 1"]
+[SetUp "1"]
+[Result "1/2-1/2"]
+
+1. Ra7 Kd8 1/2-1/2
+`
+
+func TestReadGames(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(testPgn))
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	if len(games) != 3 {
+		t.Fatalf("%s: expected 3 games got %d", t.Name(), len(games))
+	}
+
+	evergreen := games[0]
+	if white, _ := evergreen.Tag("White"); white != "Adolf Anderssen" {
+		t.Fatalf("%s: expected white to be Adolf Anderssen got %s", t.Name(), white)
+	}
+
+	if eco, _ := evergreen.Tag("ECO"); eco != "C52" {
+		t.Fatalf("%s: expected the custom ECO tag to be C52 got %s", t.Name(), eco)
+	}
+
+	if len(evergreen.Moves()) != 47 {
+		t.Fatalf("%s: expected 47 moves got %d", t.Name(), len(evergreen.Moves()))
+	}
+
+	if evergreen.Moves()[0].CommentBefore != "The Evergreen Game" {
+		t.Fatalf("%s: expected the game comment before the first move got '%s'", t.Name(), evergreen.Moves()[0].CommentBefore)
+	}
+
+	if evergreen.Status() != chess.BlackCheckmated {
+		t.Fatalf("%s: expected black to be checkmated got status %d", t.Name(), evergreen.Status())
+	}
+
+	annotated := games[1]
+	if len(annotated.Moves()) != 4 {
+		t.Fatalf("%s: expected 4 moves got %d", t.Name(), len(annotated.Moves()))
+	}
+
+	first := annotated.Moves()[0]
+	if len(first.Nags) != 1 || first.Nags[0] != 1 || first.Comment != "best by test" {
+		t.Fatalf("%s: expected 1. e4 to have nag 1 and a comment got %v '%s'", t.Name(), first.Nags, first.Comment)
+	}
+
+	second := annotated.Moves()[1]
+	if len(second.Variations) != 1 || len(second.Variations[0]) != 3 {
+		t.Fatalf("%s: expected 1... e5 to have a variation of 3 moves got %v", t.Name(), second.Variations)
+	}
+
+	sicilian := second.Variations[0]
+	if sicilian[0].Comment != "the sicilian" || len(sicilian[1].Variations) != 1 {
+		t.Fatalf("%s: expected the nested variation to be read got %v", t.Name(), sicilian)
+	}
+
+	third := annotated.Moves()[2]
+	if len(third.Nags) != 1 || third.Nags[0] != 5 || third.Comment != "a line comment" {
+		t.Fatalf("%s: expected 2. Nf3!? to have nag 5 and a comment got %v '%s'", t.Name(), third.Nags, third.Comment)
+	}
+
+	if annotated.Status() != chess.InProgress {
+		t.Fatalf("%s: expected the game to still be in progress got status %d", t.Name(), annotated.Status())
+	}
+
+	endgame := games[2]
+	if endgame.StartingFen() != "4k3/8/8/8/8/8/8/R3K3 w - - 0 1" {
+		t.Fatalf("%s: expected the game to start from the FEN tag got %s", t.Name(), endgame.StartingFen())
+	}
+
+	if endgame.Status() != chess.Draw {
+		t.Fatalf("%s: expected the game to be drawn got status %d", t.Name(), endgame.Status())
+	}
+}
+
+func TestReadGamesStreaming(t *testing.T) {
+	reader := NewReader(strings.NewReader(testPgn))
+
+	count := 0
+	for {
+		_, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+		}
+
+		count++
+	}
+
+	if count != 3 {
+		t.Fatalf("%s: expected 3 games got %d", t.Name(), count)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Pgn    string
+		Line   int
+		Column int
+	}{
+		{
+			Name:   "IllegalMove",
+			Pgn:    "[Event \"?\"]\n\n1. e4 e5\n2. Ke3 *\n",
+			Line:   4,
+			Column: 4,
+		},
+		{
+			Name:   "UnclosedTag",
+			Pgn:    "[Event \"?\"\n1. e4 *\n",
+			Line:   2,
+			Column: 1,
+		},
+		{
+			Name:   "UnclosedString",
+			Pgn:    "[Event \"?]\n",
+			Line:   1,
+			Column: 8,
+		},
+		{
+			Name:   "UnclosedVariation",
+			Pgn:    "1. e4 (1. d4 d5\n",
+			Line:   2,
+			Column: 1,
+		},
+		{
+			Name:   "UnclosedComment",
+			Pgn:    "1. e4 {never closed\n*",
+			Line:   1,
+			Column: 7,
+		},
+		{
+			Name:   "MissingResult",
+			Pgn:    "1. e4 e5\n\n[Event \"?\"]\n1. d4 *",
+			Line:   3,
+			Column: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			_, err := ReadAll(strings.NewReader(c.Pgn))
+
+			var syntaxError *SyntaxError
+			if !errors.As(err, &syntaxError) {
+				t.Fatalf("%s: expected a syntax error got %v", t.Name(), err)
+			}
+
+			if syntaxError.Line != c.Line || syntaxError.Column != c.Column {
+				t.Fatalf("%s: expected error at line %d column %d got %v", t.Name(), c.Line, c.Column, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package pgn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenType uint8
+
+const (
+	eofToken tokenType = iota
+	stringToken
+	symbolToken
+	periodToken
+	nagToken
+	commentToken
+	openBracketToken
+	closeBracketToken
+	openParenthesisToken
+	closeParenthesisToken
+)
+
+func (t tokenType) String() string {
+	switch t {
+	case eofToken:
+		return "end of file"
+	case stringToken:
+		return "string"
+	case symbolToken:
+		return "symbol"
+	case periodToken:
+		return "'.'"
+	case nagToken:
+		return "annotation"
+	case commentToken:
+		return "comment"
+	case openBracketToken:
+		return "'['"
+	case closeBracketToken:
+		return "']'"
+	case openParenthesisToken:
+		return "'('"
+	case closeParenthesisToken:
+		return "')'"
+	}
+
+	return "<unknown>"
+}
+
+// suffixAnnotations are the move suffixes that stand for numeric annotation glyphs.
+var suffixAnnotations = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+type token struct {
+	tokenType tokenType
+	value     string
+	nag       int
+	line      int
+	column    int
+}
+
+// SyntaxError is returned when the pgn can't be read, it describes where in
+// the input the problem is.
+type SyntaxError struct {
+	Line    int // The line of the problem, starting from 1.
+	Column  int // The column of the problem, starting from 1.
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("pgn: line %d column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// scanner splits pgn text into tokens, keeping track of where each token
+// starts for error messages.
+type scanner struct {
+	reader *bufio.Reader
+
+	line   int
+	column int
+
+	// the position before the last rune read so that it can be unread
+	previousLine   int
+	previousColumn int
+
+	peeked *token
+}
+
+func newScanner(reader io.Reader) *scanner {
+	return &scanner{
+		reader: bufio.NewReader(reader),
+		line:   1,
+		column: 0,
+	}
+}
+
+// errorAt creates a SyntaxError for a problem at the start of the token.
+func (s *scanner) errorAt(t token, format string, args ...any) error {
+	return &SyntaxError{Line: t.line, Column: t.column, Message: fmt.Sprintf(format, args...)}
+}
+
+// readRune reads the next rune, returning io.EOF at the end of the input.
+func (s *scanner) readRune() (rune, error) {
+	r, _, err := s.reader.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+
+	s.previousLine = s.line
+	s.previousColumn = s.column
+
+	if r == '\n' {
+		s.line++
+		s.column = 0
+	} else {
+		s.column++
+	}
+
+	return r, nil
+}
+
+func (s *scanner) unreadRune() {
+	s.reader.UnreadRune()
+	s.line = s.previousLine
+	s.column = s.previousColumn
+}
+
+// peek returns the next token without consuming it.
+func (s *scanner) peek() (token, error) {
+	if s.peeked == nil {
+		t, err := s.scan()
+		if err != nil {
+			return t, err
+		}
+
+		s.peeked = &t
+	}
+
+	return *s.peeked, nil
+}
+
+// next consumes and returns the next token.
+func (s *scanner) next() (token, error) {
+	t, err := s.peek()
+	s.peeked = nil
+
+	return t, err
+}
+
+// scan reads the next token from the input.
+func (s *scanner) scan() (token, error) {
+	for {
+		r, err := s.readRune()
+		if errors.Is(err, io.EOF) {
+			return token{tokenType: eofToken, line: s.line, column: s.column + 1}, nil
+		} else if err != nil {
+			return token{}, err
+		}
+
+		if unicode.IsSpace(r) {
+			continue
+		}
+
+		t := token{line: s.line, column: s.column}
+
+		switch {
+		case r == '%' && s.column == 1:
+			// escaped lines are for other programs and are ignored
+			_, err := s.readUntil('\n')
+			if err != nil {
+				return t, err
+			}
+			continue
+		case r == '[':
+			t.tokenType = openBracketToken
+			return t, nil
+		case r == ']':
+			t.tokenType = closeBracketToken
+			return t, nil
+		case r == '(':
+			t.tokenType = openParenthesisToken
+			return t, nil
+		case r == ')':
+			t.tokenType = closeParenthesisToken
+			return t, nil
+		case r == '.':
+			t.tokenType = periodToken
+			return t, nil
+		case r == '"':
+			return s.scanString(t)
+		case r == '{':
+			t.tokenType = commentToken
+			t.value, err = s.readUntil('}')
+			if errors.Is(err, io.EOF) {
+				return t, s.errorAt(t, "comment is never closed")
+			}
+			return t, err
+		case r == ';':
+			t.tokenType = commentToken
+			t.value, err = s.readUntil('\n')
+			return t, err
+		case r == '$':
+			return s.scanNag(t)
+		case r == '!' || r == '?':
+			return s.scanSuffixAnnotation(t, r)
+		case r == '*' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			return s.scanSymbol(t, r)
+		}
+
+		return t, s.errorAt(t, "unexpected character '%c'", r)
+	}
+}
+
+// readUntil reads up to and including the delimiter, returning the text
+// before it. The end of the input counts as a delimiter for line ends.
+func (s *scanner) readUntil(delimiter rune) (string, error) {
+	var builder strings.Builder
+
+	for {
+		r, err := s.readRune()
+		if errors.Is(err, io.EOF) && delimiter == '\n' {
+			return builder.String(), nil
+		} else if err != nil {
+			return builder.String(), err
+		}
+
+		if r == delimiter {
+			return builder.String(), nil
+		}
+
+		builder.WriteRune(r)
+	}
+}
+
+func (s *scanner) scanString(t token) (token, error) {
+	var builder strings.Builder
+
+	t.tokenType = stringToken
+	for {
+		r, err := s.readRune()
+		if errors.Is(err, io.EOF) || r == '\n' {
+			return t, s.errorAt(t, "string is never closed")
+		} else if err != nil {
+			return t, err
+		}
+
+		switch r {
+		case '"':
+			t.value = builder.String()
+			return t, nil
+		case '\\':
+			// a backslash escapes quotes and backslashes
+			escaped, err := s.readRune()
+			if err != nil {
+				return t, s.errorAt(t, "string is never closed")
+			}
+
+			builder.WriteRune(escaped)
+			break
+		default:
+			builder.WriteRune(r)
+			break
+		}
+	}
+}
+
+func (s *scanner) scanNag(t token) (token, error) {
+	var builder strings.Builder
+
+	for {
+		r, err := s.readRune()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return t, err
+		}
+
+		if !unicode.IsDigit(r) {
+			s.unreadRune()
+			break
+		}
+
+		builder.WriteRune(r)
+	}
+
+	nag, err := strconv.Atoi(builder.String())
+	if err != nil || nag > 255 {
+		return t, s.errorAt(t, "invalid numeric annotation glyph '$%s'", builder.String())
+	}
+
+	t.tokenType = nagToken
+	t.nag = nag
+
+	return t, nil
+}
+
+func (s *scanner) scanSuffixAnnotation(t token, first rune) (token, error) {
+	suffix := string(first)
+
+	r, err := s.readRune()
+	if err == nil {
+		if r == '!' || r == '?' {
+			suffix += string(r)
+		} else {
+			s.unreadRune()
+		}
+	} else if !errors.Is(err, io.EOF) {
+		return t, err
+	}
+
+	t.tokenType = nagToken
+	t.value = suffix
+	t.nag = suffixAnnotations[suffix]
+
+	return t, nil
+}
+
+// isSymbolContinuation returns whether the rune can be part of a symbol after its first character.
+func isSymbolContinuation(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_+#=:-/", r)
+}
+
+func (s *scanner) scanSymbol(t token, first rune) (token, error) {
+	var builder strings.Builder
+	builder.WriteRune(first)
+
+	t.tokenType = symbolToken
+
+	// * is a symbol by itself, it's the result of an unfinished game
+	if first == '*' {
+		t.value = builder.String()
+		return t, nil
+	}
+
+	for {
+		r, err := s.readRune()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return t, err
+		}
+
+		if !isSymbolContinuation(r) {
+			s.unreadRune()
+			break
+		}
+
+		builder.WriteRune(r)
+	}
+
+	t.value = builder.String()
+
+	return t, nil
+}
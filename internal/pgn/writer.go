@@ -0,0 +1,209 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"rosaline/internal/chess"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxLineLength = 79 // Lines of movetext are kept shorter than 80 characters.
+)
+
+// rosterDefaults are the values written for tags of the seven tag roster
+// that the game doesn't have.
+var rosterDefaults = map[string]string{
+	"Date": "????.??.??",
+}
+
+// Result returns the result token for the status of a game.
+func Result(status chess.GameStatus) string {
+	switch status {
+	case chess.WhiteCheckmated, chess.WhiteResigned:
+		return BlackWinsResult
+	case chess.BlackCheckmated, chess.BlackResigned:
+		return WhiteWinsResult
+	case chess.Draw, chess.Stalemate:
+		return DrawResult
+	}
+
+	return UnknownResult
+}
+
+// Write writes the game as pgn followed by a blank line so that several
+// games can be written one after the other.
+//
+// The tags of the seven tag roster are always written first, the result is
+// taken from the status of the game rather than its Result tag.
+func Write(writer io.Writer, game chess.Game) error {
+	var builder strings.Builder
+
+	result := Result(game.Status())
+	writeTags(&builder, game, result)
+	builder.WriteString("\n")
+
+	position, err := chess.NewPosition(game.StartingFen())
+	if err != nil {
+		return err
+	}
+
+	position.SetChess960(game.Position.IsChess960())
+
+	tokens := movetextTokens(position, game.Moves(), []string{})
+	tokens = append(tokens, result)
+	writeWrapped(&builder, tokens)
+	builder.WriteString("\n")
+
+	_, err = io.WriteString(writer, builder.String())
+	return err
+}
+
+// String returns the game as pgn.
+func String(game chess.Game) (string, error) {
+	var builder strings.Builder
+	err := Write(&builder, game)
+
+	return builder.String(), err
+}
+
+func writeTag(builder *strings.Builder, name string, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+
+	fmt.Fprintf(builder, "[%s \"%s\"]\n", name, value)
+}
+
+// writeTags writes the seven tag roster followed by the game's other tags,
+// adding the tags needed to set up games that don't start from the standard
+// starting position.
+func writeTags(builder *strings.Builder, game chess.Game, result string) {
+	for _, name := range chess.SevenTagRoster {
+		value, ok := game.Tag(name)
+		if name == "Result" {
+			value = result
+		} else if !ok {
+			value, ok = rosterDefaults[name]
+			if !ok {
+				value = "?"
+			}
+		}
+
+		writeTag(builder, name, value)
+	}
+
+	written := map[string]bool{}
+	for _, name := range chess.SevenTagRoster {
+		written[name] = true
+	}
+
+	if game.Position.IsChess960() {
+		if _, ok := game.Tag("Variant"); !ok {
+			writeTag(builder, "Variant", "Chess960")
+		}
+	}
+
+	if game.StartingFen() != chess.StartingFen || game.Position.IsChess960() {
+		writeTag(builder, "SetUp", "1")
+		writeTag(builder, "FEN", game.StartingFen())
+		written["SetUp"] = true
+		written["FEN"] = true
+	}
+
+	for _, tag := range game.Tags() {
+		if !written[tag.Name] {
+			writeTag(builder, tag.Name, tag.Value)
+		}
+	}
+}
+
+// movetextTokens adds the tokens making up the movetext for the moves played
+// from the given position, including the moves of their variations.
+func movetextTokens(position chess.Position, moves []chess.GameMove, tokens []string) []string {
+	// black's moves only need a move number at the start or after an interruption
+	needsNumber := true
+
+	for _, move := range moves {
+		if move.CommentBefore != "" {
+			tokens = append(tokens, comment(move.CommentBefore))
+			needsNumber = true
+		}
+
+		if position.Turn() == chess.White {
+			tokens = append(tokens, strconv.Itoa(position.FullMoves())+".")
+		} else if needsNumber {
+			tokens = append(tokens, strconv.Itoa(position.FullMoves())+"...")
+		}
+
+		tokens = append(tokens, position.San(move.Move))
+		needsNumber = false
+
+		for _, nag := range move.Nags {
+			tokens = append(tokens, "$"+strconv.Itoa(nag))
+		}
+
+		text := move.Comment
+		if move.Evaluation != nil {
+			text = strings.TrimSpace(formatEvaluation(*move.Evaluation) + " " + text)
+		}
+
+		if text != "" {
+			tokens = append(tokens, comment(text))
+			needsNumber = true
+		}
+
+		for _, variation := range move.Variations {
+			tokens = append(tokens, "(")
+			tokens = movetextTokens(position, variation, tokens)
+			tokens = append(tokens, ")")
+			needsNumber = true
+		}
+
+		position.MakeMove(move.Move)
+	}
+
+	return tokens
+}
+
+// comment returns the comment token for the text. Comments can't contain
+// a closing brace so any are removed.
+func comment(text string) string {
+	return "{" + strings.ReplaceAll(text, "}", "") + "}"
+}
+
+// formatEvaluation returns the evaluation as an [%eval] command, the way
+// chess guis embed evaluations in comments.
+func formatEvaluation(evaluation chess.Evaluation) string {
+	if evaluation.Mate {
+		return fmt.Sprintf("[%%eval #%d]", evaluation.Score)
+	}
+
+	return fmt.Sprintf("[%%eval %.2f]", float64(evaluation.Score)/100)
+}
+
+// writeWrapped writes the tokens separated by spaces, starting a new line
+// before a line would become too long. There is no space after an opening
+// or before a closing parenthesis.
+func writeWrapped(builder *strings.Builder, tokens []string) {
+	lineLength := 0
+
+	for i, token := range tokens {
+		separator := " "
+		if i == 0 || tokens[i-1] == "(" || token == ")" {
+			separator = ""
+		}
+
+		if lineLength > 0 && lineLength+len(separator)+len(token) > maxLineLength {
+			builder.WriteString("\n")
+			lineLength = 0
+			separator = ""
+		}
+
+		builder.WriteString(separator)
+		builder.WriteString(token)
+		lineLength += len(separator) + len(token)
+	}
+
+	builder.WriteString("\n")
+}
@@ -0,0 +1,134 @@
+package pgn
+
+import (
+	"rosaline/internal/chess"
+	"strings"
+	"testing"
+)
+
+func TestResult(t *testing.T) {
+	cases := []struct {
+		Status chess.GameStatus
+		Result string
+	}{
+		{Status: chess.InProgress, Result: UnknownResult},
+		{Status: chess.WhiteCheckmated, Result: BlackWinsResult},
+		{Status: chess.BlackCheckmated, Result: WhiteWinsResult},
+		{Status: chess.WhiteResigned, Result: BlackWinsResult},
+		{Status: chess.BlackResigned, Result: WhiteWinsResult},
+		{Status: chess.Draw, Result: DrawResult},
+		{Status: chess.Stalemate, Result: DrawResult},
+	}
+
+	for _, c := range cases {
+		if Result(c.Status) != c.Result {
+			t.Fatalf("%s: expected %s for status %d got %s", t.Name(), c.Result, c.Status, Result(c.Status))
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	game, _ := chess.NewGame(chess.StartingFen)
+	game.SetTag("White", "rosaline")
+	game.SetTag("Black", "rosaline")
+	game.SetTag("TimeControl", "60+1")
+
+	for _, san := range []string{"f3", "e5", "g4", "Qh4#"} {
+		err := game.MakeSanMove(san)
+		if err != nil {
+			t.Fatalf("%s: %s returned error: %s", t.Name(), san, err)
+		}
+	}
+
+	game.LastMove().Comment = "fool's mate"
+	game.LastMove().Evaluation = &chess.Evaluation{Score: 0, Mate: true}
+	game.Moves()[1].Nags = []int{1}
+	game.Moves()[2].Variations = [][]chess.GameMove{
+		{
+			{Move: findMove(t, "rnbqkbnr/pppp1ppp/8/4p3/8/5P2/PPPPP1PP/RNBQKBNR w KQkq - 0 2", "Nc3")},
+		},
+	}
+
+	pgn, err := String(game)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	expected := `[Event "?"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "rosaline"]
+[Black "rosaline"]
+[Result "0-1"]
+[TimeControl "60+1"]
+
+1. f3 e5 $1 2. g4 (2. Nc3) 2... Qh4# {[%eval #0] fool's mate} 0-1
+
+`
+
+	if pgn != expected {
+		t.Fatalf("%s: expected\n%s\ngot\n%s", t.Name(), expected, pgn)
+	}
+}
+
+func findMove(t *testing.T, fen string, san string) chess.Move {
+	position, _ := chess.NewPosition(fen)
+
+	move, err := position.ParseSan(san)
+	if err != nil {
+		t.Fatalf("%s: %s returned error: %s", t.Name(), san, err)
+	}
+
+	return move
+}
+
+func TestWriteSetUp(t *testing.T) {
+	game, _ := chess.NewGame("k7/8/1K6/8/8/8/8/7R b - - 0 1")
+	game.MakeSanMove("Kb8")
+	game.MakeSanMove("Rh8#")
+
+	pgn, _ := String(game)
+	if !strings.Contains(pgn, "[SetUp \"1\"]\n[FEN \"k7/8/1K6/8/8/8/8/7R b - - 0 1\"]\n") {
+		t.Fatalf("%s: expected SetUp and FEN tags got\n%s", t.Name(), pgn)
+	}
+
+	if !strings.Contains(pgn, "\n1... Kb8 2. Rh8# 1-0\n") {
+		t.Fatalf("%s: expected movetext starting with black's move got\n%s", t.Name(), pgn)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(testPgn))
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+	}
+
+	var builder strings.Builder
+	for _, game := range games {
+		err := Write(&builder, game)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", t.Name(), err)
+		}
+	}
+
+	for _, line := range strings.Split(builder.String(), "\n") {
+		if len(line) > maxLineLength {
+			t.Fatalf("%s: line is longer than %d characters: %s", t.Name(), maxLineLength, line)
+		}
+	}
+
+	reread, err := ReadAll(strings.NewReader(builder.String()))
+	if err != nil {
+		t.Fatalf("%s: reading the written games returned error: %v\n%s", t.Name(), err, builder.String())
+	}
+
+	var rewritten strings.Builder
+	for _, game := range reread {
+		Write(&rewritten, game)
+	}
+
+	if rewritten.String() != builder.String() {
+		t.Fatalf("%s: expected writing the games again to give\n%s\ngot\n%s", t.Name(), builder.String(), rewritten.String())
+	}
+}
@@ -18,8 +18,15 @@ func newDrawTable() drawTable {
 }
 
 // Push addes a new hash to the draw table.
+//
+// The table grows when it is full so long games can be stored in full.
 func (t *drawTable) Push(hash uint64) {
-	t.hashes[t.index] = hash
+	if t.index >= len(t.hashes) {
+		t.hashes = append(t.hashes, hash)
+	} else {
+		t.hashes[t.index] = hash
+	}
+
 	t.index++
 }
 
@@ -40,3 +40,16 @@ func TestIsRepeat(t *testing.T) {
 		t.Fatalf("%s: expected to be a draw but one was not found", t.Name())
 	}
 }
+
+func TestPushGrows(t *testing.T) {
+	table := newDrawTable()
+
+	for i := 0; i <= maxDrawTableSize; i++ {
+		table.Push(uint64(i))
+	}
+
+	popped, ok := table.Pop()
+	if !ok || popped != uint64(maxDrawTableSize) {
+		t.Fatalf("%s: expected hash '%v' got '%v'", t.Name(), maxDrawTableSize, popped)
+	}
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"fmt"
+	"rosaline/internal/chess"
+	"rosaline/internal/evaluation"
+	"time"
+)
+
+const (
+	infoInterval = time.Second // How often the progress of a long search is reported.
+)
+
+// mateIn returns the number of moves until mate for a mate score. The number
+// is negative if the player to move is getting mated.
+func mateIn(score int) int {
+	if score > 0 {
+		return (evaluation.MateScore - score + 1) / 2
+	}
+
+	return -(evaluation.MateScore + score) / 2
+}
+
+// formatScore formats the score for uci output.
+func formatScore(score int) string {
+	if evaluation.IsMateScore(score) {
+		return fmt.Sprintf("mate %d", mateIn(score))
+	}
+
+	return fmt.Sprintf("cp %d", score)
+}
+
+// nps returns the number of nodes searched per second.
+func (s *NegamaxSearcher) nps() int {
+	elapsed := time.Since(s.start)
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return int(float64(s.nodes) / elapsed.Seconds())
+}
+
+// printLine prints the information about a line found by the search.
+//
+// bound should either be empty for an exact score or lowerbound/upperbound if
+// the score fell outside of the aspiration window.
+func (s *NegamaxSearcher) printLine(depth int, multiPV int, line SearchLine, bound string) {
+	score := formatScore(line.Score)
+	if bound != "" {
+		score += " " + bound
+	}
+
+	if s.showWDL {
+		win, draw, loss := evaluation.WinDrawLoss(line.Score, s.rootPly)
+		score += fmt.Sprintf(" wdl %d %d %d", win, draw, loss)
+	}
+
+	elapsed := time.Since(s.start)
+	fmt.Fprintf(s.output, "info depth %d seldepth %d multipv %d score %s nodes %d nps %d hashfull %d time %d pv %s\n", depth, s.selDepth, multiPV, score, s.nodes, s.nps(), s.ttable.Hashfull(), elapsed.Milliseconds(), line.Uci(s.chess960))
+
+	s.lastInfo = time.Now()
+}
+
+// printCurrentMove prints the root move that is currently being searched
+// once the search has been running long enough for it to be useful.
+func (s *NegamaxSearcher) printCurrentMove(depth int, move chess.Move, number int) {
+	if !s.print || time.Since(s.start) < infoInterval {
+		return
+	}
+
+	fmt.Fprintf(s.output, "info depth %d currmove %s currmovenumber %d\n", depth, move.Uci(s.chess960), number)
+}
+
+// printProgress periodically prints the number of nodes searched during long iterations.
+func (s *NegamaxSearcher) printProgress() {
+	if !s.print || time.Since(s.lastInfo) < infoInterval {
+		return
+	}
+
+	elapsed := time.Since(s.start)
+	fmt.Fprintf(s.output, "info nodes %d nps %d hashfull %d time %d\n", s.nodes, s.nps(), s.ttable.Hashfull(), elapsed.Milliseconds())
+
+	s.lastInfo = time.Now()
+}
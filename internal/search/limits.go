@@ -0,0 +1,57 @@
+package search
+
+import (
+	"rosaline/internal/chess"
+	"slices"
+	"time"
+)
+
+// SearchLimits are the constraints that a search has to finish within.
+//
+// A zero value for any of the limits means that limit is not used.
+type SearchLimits struct {
+	Depth    int           // The maximum depth to search to.
+	Nodes    int           // The maximum number of nodes to search.
+	MoveTime time.Duration // The exact amount of time to search for.
+	Infinite bool          // Whether to search until told to stop.
+	Ponder   bool          // Whether to search the predicted position without a time limit until the opponent moves.
+	Mate     int           // The number of moves to look for a forced mate in before searching normally.
+
+	SearchMoves []chess.Move // The root moves to search, all moves are searched when empty.
+
+	WhiteTime      time.Duration // The time white has left on the clock.
+	BlackTime      time.Duration // The time black has left on the clock.
+	WhiteIncrement time.Duration // White's increment per move.
+	BlackIncrement time.Duration // Black's increment per move.
+	MovesToGo      int           // The number of moves until the next time control.
+}
+
+// NewDepthLimits creates SearchLimits that only limit the depth of the search.
+func NewDepthLimits(depth int) SearchLimits {
+	return SearchLimits{
+		Depth: depth,
+	}
+}
+
+// maxDepth returns the maximum depth the search is allowed to reach.
+func (l SearchLimits) maxDepth() int {
+	if l.Depth <= 0 || l.Depth > MaxDepth {
+		return MaxDepth
+	}
+
+	return l.Depth
+}
+
+// clock returns the remaining time and increment for the given color.
+func (l SearchLimits) clock(turn chess.Color) (time.Duration, time.Duration) {
+	if turn == chess.White {
+		return l.WhiteTime, l.WhiteIncrement
+	}
+
+	return l.BlackTime, l.BlackIncrement
+}
+
+// allows returns whether the move can be searched at the root.
+func (l SearchLimits) allows(move chess.Move) bool {
+	return len(l.SearchMoves) == 0 || slices.Contains(l.SearchMoves, move)
+}
@@ -0,0 +1,223 @@
+package search
+
+import (
+	"context"
+	"rosaline/internal/chess"
+	"rosaline/internal/evaluation"
+	"slices"
+	"time"
+)
+
+const (
+	MaxMateMoves = maxPly / 2 // The longest mate the mate search can look for.
+)
+
+// SetMateChecksOnly sets whether the mate search only considers checking
+// moves for the attacking side. This finds mates made up of checks much
+// faster but misses mates that need a quiet move.
+func (s *NegamaxSearcher) SetMateChecksOnly(checksOnly bool) {
+	s.mateChecksOnly = checksOnly
+}
+
+// SearchMate looks for a forced mate in at most the given number of moves.
+//
+// If a mate is found the line leading to it is returned, the search stops as
+// soon as the shortest mate has been proven or all mates have been refuted,
+// or once ctx is cancelled.
+func (s *NegamaxSearcher) SearchMate(ctx context.Context, position chess.Position, moves int) (SearchLine, bool) {
+	s.ClearPreviousSearch()
+	s.ctx = ctx
+
+	s.limits = SearchLimits{Mate: moves}
+	s.timeManager = newTimeManager(s.limits, position.Turn(), s.moveOverhead)
+	s.print = false
+	s.chess960 = position.IsChess960()
+	s.rootPly = position.Plies()
+	s.start = time.Now()
+	s.lastInfo = s.start
+
+	line, found := s.searchMate(&position, moves)
+	if found {
+		s.lines = append(s.lines, line)
+	}
+
+	return line, found
+}
+
+// searchMate looks for the shortest forced mate within the given number of
+// moves by searching for a mate in one move, then two moves and so on.
+func (s *NegamaxSearcher) searchMate(position *chess.Position, moves int) (SearchLine, bool) {
+	moves = min(moves, MaxMateMoves)
+
+	for n := 1; n <= moves; n++ {
+		s.selDepth = 0
+
+		if !s.attack(position, n, 0) {
+			if s.stop {
+				break
+			}
+
+			continue
+		}
+
+		line := SearchLine{
+			Score: evaluation.MateScore - (2*n - 1),
+			Moves: slices.Clone(s.pvtable[0][:s.pvlength[0]]),
+		}
+
+		if s.print {
+			s.printLine(2*n-1, 1, line, "")
+		}
+
+		return line, true
+	}
+
+	return SearchLine{}, false
+}
+
+// attack returns whether the player to move can force mate within the given
+// number of moves.
+func (s *NegamaxSearcher) attack(position *chess.Position, moves int, ply int) bool {
+	s.pvlength[ply] = ply
+	s.selDepth = max(s.selDepth, ply)
+
+	s.nodes++
+	s.checkLimits()
+	if s.stop {
+		return false
+	}
+
+	if position.IsDraw() {
+		return false
+	}
+
+	var candidates chess.MoveList
+	s.mateCandidates(position, moves, &candidates)
+
+	for i := 0; i < candidates.Len(); i++ {
+		move := candidates.Move(i)
+		if ply == 0 && !s.limits.allows(move) {
+			continue
+		}
+
+		position.MakeMove(move)
+		mated := s.defend(position, moves, ply+1)
+		position.UndoInPlace()
+
+		if s.stop {
+			return false
+		}
+
+		if mated {
+			s.pvtable[ply][ply] = move
+			for i := ply + 1; i < s.pvlength[ply+1]; i++ {
+				s.pvtable[ply][i] = s.pvtable[ply+1][i]
+			}
+
+			s.pvlength[ply] = s.pvlength[ply+1]
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// defend returns whether every move of the player to move leads to them
+// being mated within the given number of moves, counting the attacker's
+// move that led to this position.
+//
+// The line that resists the longest is kept as the principal variation.
+func (s *NegamaxSearcher) defend(position *chess.Position, moves int, ply int) bool {
+	s.pvlength[ply] = ply
+	s.selDepth = max(s.selDepth, ply)
+
+	s.nodes++
+	s.checkLimits()
+	if s.stop {
+		return false
+	}
+
+	var replies chess.MoveList
+	position.GenerateMoveList(chess.LegalMoveGeneration, &replies)
+	if replies.Len() == 0 {
+		return position.IsKingInCheck(position.Turn())
+	}
+
+	if moves == 1 || position.IsDraw() {
+		return false
+	}
+
+	longest := 0
+	for i := 0; i < replies.Len(); i++ {
+		reply := replies.Move(i)
+		position.MakeMove(reply)
+		mated := s.attack(position, moves-1, ply+1)
+		position.UndoInPlace()
+
+		if !mated {
+			return false
+		}
+
+		if s.pvlength[ply+1] > longest {
+			longest = s.pvlength[ply+1]
+
+			s.pvtable[ply][ply] = reply
+			for i := ply + 1; i < s.pvlength[ply+1]; i++ {
+				s.pvtable[ply][i] = s.pvtable[ply+1][i]
+			}
+
+			s.pvlength[ply] = s.pvlength[ply+1]
+		}
+	}
+
+	return true
+}
+
+// mateCandidates fills the list with the moves the attacker should try to
+// force mate, checking moves come first followed by captures and then quiet
+// moves.
+//
+// With only one move left the moves that don't give check can't lead to mate
+// so they are left out, as they are when configured to only consider checks.
+func (s *NegamaxSearcher) mateCandidates(position *chess.Position, moves int, list *chess.MoveList) {
+	const (
+		quiet = iota
+		capture
+		check
+	)
+
+	var all chess.MoveList
+	position.GenerateMoveList(chess.LegalMoveGeneration, &all)
+
+	for i := 0; i < all.Len(); i++ {
+		move := all.Move(i)
+
+		position.MakeMove(move)
+		inCheck := position.IsKingInCheck(position.Turn())
+		position.UndoInPlace()
+
+		if inCheck {
+			all.SetScore(i, check)
+		} else if move.IsCapture() {
+			all.SetScore(i, capture)
+		} else {
+			all.SetScore(i, quiet)
+		}
+	}
+
+	lowest := quiet
+	if moves == 1 || s.mateChecksOnly {
+		lowest = check
+	}
+
+	// each kind of move is added in a separate pass to keep the moves in the order they were generated
+	list.Clear()
+	for kind := check; kind >= lowest; kind-- {
+		for i := 0; i < all.Len(); i++ {
+			if all.Score(i) == kind {
+				list.Add(all.Move(i))
+			}
+		}
+	}
+}
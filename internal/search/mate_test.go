@@ -0,0 +1,76 @@
+package search
+
+import (
+	"context"
+	"rosaline/internal/chess"
+	"rosaline/internal/evaluation"
+	"testing"
+)
+
+func searchMateTest(t *testing.T, fen string, moves int, checksOnly bool, expected string) {
+	position, err := chess.NewPosition(fen)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err)
+	}
+
+	searcher := NewNegamaxSearcher(evaluation.NewEvaluator())
+	searcher.SetMateChecksOnly(checksOnly)
+
+	line, found := searcher.SearchMate(context.Background(), position, moves)
+	if expected == "" {
+		if found {
+			t.Fatalf("%s: expected no mate in %d got %s", t.Name(), moves, line)
+		}
+
+		return
+	}
+
+	if !found {
+		t.Fatalf("%s: expected mate %s to be found", t.Name(), expected)
+	}
+
+	if line.String() != expected {
+		t.Fatalf("%s: expected mate %s got %s", t.Name(), expected, line)
+	}
+
+	if mateIn(line.Score) != (len(line.Moves)+1)/2 {
+		t.Fatalf("%s: expected the score to be mate in %d got %s", t.Name(), (len(line.Moves)+1)/2, formatScore(line.Score))
+	}
+}
+
+func TestSearchMate(t *testing.T) {
+	cases := []struct {
+		Name       string
+		Fen        string
+		Moves      int
+		ChecksOnly bool
+		Expected   string
+	}{
+		{Name: "mate in 1", Fen: "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", Moves: 1, Expected: "a1a8"},
+		{Name: "shortest mate", Fen: "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", Moves: 3, Expected: "a1a8"},
+		{Name: "mate in 2", Fen: "r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1", Moves: 2, Expected: "d5f6 g7f6 c4f7"},
+		{Name: "quiet mate in 2", Fen: "k7/8/2K5/8/8/8/8/6R1 w - - 0 1", Moves: 2, Expected: "c6b6 a8b8 g1g8"},
+		{Name: "quiet mate with checks only", Fen: "k7/8/2K5/8/8/8/8/6R1 w - - 0 1", Moves: 2, ChecksOnly: true, Expected: ""},
+		{Name: "no mate", Fen: chess.StartingFen, Moves: 2, Expected: ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			searchMateTest(t, c.Fen, c.Moves, c.ChecksOnly, c.Expected)
+		})
+	}
+}
+
+func TestSearchWithMateLimit(t *testing.T) {
+	position, _ := chess.NewPosition("r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1")
+	searcher := NewNegamaxSearcher(evaluation.NewEvaluator())
+
+	bestMove := searcher.Search(context.Background(), position, SearchLimits{Mate: 2}, false)
+	if bestMove.String() != "d5f6" {
+		t.Fatalf("%s: expected best move d5f6 got %s", t.Name(), bestMove)
+	}
+
+	if searcher.PonderMove().String() != "g7f6" {
+		t.Fatalf("%s: expected ponder move g7f6 got %s", t.Name(), searcher.PonderMove())
+	}
+}
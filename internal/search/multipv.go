@@ -0,0 +1,99 @@
+package search
+
+import (
+	"cmp"
+	"rosaline/internal/chess"
+	"slices"
+	"strings"
+)
+
+const (
+	MaxMultiPV = 256
+)
+
+// SearchLine is one of the lines found during a search.
+type SearchLine struct {
+	Score int          // The score of the line from the perspective of the player to move.
+	Moves []chess.Move // The moves of the line, starting with the move to play.
+}
+
+func (l SearchLine) String() string {
+	return l.Uci(false)
+}
+
+// Uci returns the moves of the line in uci notation, with castling written
+// as the king capturing its own rook when chess960 is set.
+func (l SearchLine) Uci(chess960 bool) string {
+	moves := make([]string, len(l.Moves))
+	for i, move := range l.Moves {
+		moves[i] = move.Uci(chess960)
+	}
+
+	return strings.Join(moves, " ")
+}
+
+// SetMultiPV sets the number of lines to search for.
+func (s *NegamaxSearcher) SetMultiPV(lines int) {
+	s.multiPV = min(max(lines, 1), MaxMultiPV)
+}
+
+// Lines returns the lines found by the last completed iteration of the
+// search, ordered from best to worst.
+func (s *NegamaxSearcher) Lines() []SearchLine {
+	return s.lines
+}
+
+// searchLines searches the root position to the given depth once for each
+// line. Each search excludes the first moves of the lines that have already
+// been found so that every line starts with a different move.
+//
+// The first line is searched using the given aspiration window, false is
+// returned if its score falls outside of it or if the search was stopped
+// before the first line was found. When the score falls outside of the window
+// the failed line is the only line returned.
+func (s *NegamaxSearcher) searchLines(position *chess.Position, depth int, numLines int, alpha int, beta int) ([]SearchLine, bool) {
+	lines := make([]SearchLine, 0, numLines)
+
+	s.excludedMoves = s.excludedMoves[:0]
+	defer func() {
+		s.excludedMoves = s.excludedMoves[:0]
+	}()
+
+	for k := 0; k < numLines; k++ {
+		lineAlpha := initialAlpha
+		lineBeta := initialBeta
+		if k == 0 {
+			lineAlpha = alpha
+			lineBeta = beta
+		}
+
+		score := s.doSearch(position, lineAlpha, lineBeta, depth, 0, 0)
+		if s.stop {
+			break
+		}
+
+		moves := slices.Clone(s.pvtable[0][:s.pvlength[0]])
+
+		if k == 0 && (score <= alpha || score >= beta) {
+			return []SearchLine{{Score: score, Moves: moves}}, false
+		}
+
+		if len(moves) == 0 {
+			break
+		}
+
+		lines = append(lines, SearchLine{Score: score, Moves: moves})
+		s.excludedMoves = append(s.excludedMoves, moves[0])
+	}
+
+	if len(lines) == 0 {
+		return lines, false
+	}
+
+	// later lines can score higher than earlier ones due to search instability
+	slices.SortStableFunc(lines, func(l1, l2 SearchLine) int {
+		return cmp.Compare(l2.Score, l1.Score)
+	})
+
+	return lines, true
+}
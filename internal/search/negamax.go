@@ -1,13 +1,16 @@
 package search
 
 import (
-	"cmp"
+	"context"
 	"fmt"
+	"io"
 	"math"
+	"math/rand"
+	"os"
 	"rosaline/internal/chess"
 	"rosaline/internal/evaluation"
 	"slices"
-	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,6 +25,8 @@ const (
 	window = 50
 
 	MaxDepth = 16
+
+	maxPly = MaxDepth * 2 // Must not be more than evaluation.MaxMatePly so every mate found is reported as one.
 )
 
 type NegamaxSearcher struct {
@@ -33,37 +38,143 @@ type NegamaxSearcher struct {
 
 	ttable TranspositionTable
 
-	pvtable  [MaxDepth][MaxDepth]chess.Move
-	pvlength [MaxDepth]int
+	pvtable  [maxPly][maxPly]chess.Move
+	pvlength [maxPly]int
 
+	ctx  context.Context // Cancelled when the search has to stop.
 	stop bool
 
+	pondering  bool
+	ponderHit  atomic.Bool   // Set from outside the search so it has to be safe for concurrent use.
+	ponderHits chan struct{} // Wakes up a finished search that is waiting for a ponderhit.
+	ponderMove chess.Move
+
+	multiPV       int
+	lines         []SearchLine
+	excludedMoves []chess.Move
+
+	mateChecksOnly bool
+
+	skill  Skill
+	random *rand.Rand
+
+	limits       SearchLimits
+	timeManager  timeManager
+	moveOverhead time.Duration
+
+	output   io.Writer
+	print    bool
+	showWDL  bool
+	chess960 bool // Whether castling moves are printed as the king capturing its own rook.
+	rootPly  int
+	start    time.Time
+	lastInfo time.Time
+	selDepth int
+
 	nodes int
 }
 
-func NewNegamaxSearcher(evaluator evaluation.Evaluator) NegamaxSearcher {
-	return NegamaxSearcher{
+func NewNegamaxSearcher(evaluator evaluation.Evaluator) *NegamaxSearcher {
+	return &NegamaxSearcher{
 		evaluator:       evaluator,
 		drawTable:       newDrawTable(),
 		killerMoves:     make(map[chess.Color][]chess.Move),
 		killerMoveIndex: 0,
-		ttable:          NewTranspositionTable(),
+		ponderHits:      make(chan struct{}, 1),
+		ttable:          NewTranspositionTable(DefaultTableSize),
+		moveOverhead:    DefaultMoveOverhead,
+		output:          os.Stdout,
+		multiPV:         1,
+		skill:           FullStrength(),
+		random:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		lines:           []SearchLine{},
+		excludedMoves:   []chess.Move{},
 		nodes:           0,
 	}
 }
 
-func (s *NegamaxSearcher) Search(position chess.Position, depth int, print bool) chess.Move {
+// Search finds the best move in the position while staying within the given limits.
+//
+// The search stops early once ctx is cancelled, returning the best move found
+// so far. Searches that are infinite or pondering only return once ctx is
+// cancelled or, when pondering, after PonderHit is called.
+func (s *NegamaxSearcher) Search(ctx context.Context, position chess.Position, limits SearchLimits, print bool) chess.Move {
 	s.ClearPreviousSearch()
+	s.ctx = ctx
+
+	if s.skill.Enabled() {
+		limits = s.skill.limit(limits)
+	}
+
+	s.limits = limits
+	s.timeManager = newTimeManager(limits, position.Turn(), s.moveOverhead)
+	s.print = print
+	s.chess960 = position.IsChess960()
+	s.rootPly = position.Plies()
+	s.start = time.Now()
+	s.lastInfo = s.start
+	s.pondering = limits.Ponder
+	s.ponderMove = chess.NullMove
+
+	rootMoves := slices.DeleteFunc(position.GenerateMoves(chess.LegalMoveGeneration), func(move chess.Move) bool {
+		return !limits.allows(move)
+	})
+	numLines := min(s.multiPV, len(rootMoves))
+	if s.skill.Enabled() {
+		// a weaker skill level needs a few lines to choose from
+		numLines = min(max(s.multiPV, skillCandidates), len(rootMoves))
+	}
 
 	bestMove := chess.NullMove
+	bestScore := 0
 	alpha := initialAlpha
 	beta := initialBeta
 
+	depth := limits.maxDepth()
+	if len(rootMoves) == 0 {
+		depth = 0 // the game is over, there is nothing to search
+	}
+
+	if limits.Mate > 0 && depth > 0 {
+		line, found := s.searchMate(&position, limits.Mate)
+		if found {
+			s.lines = append(s.lines, line)
+			bestMove = line.Moves[0]
+			if len(line.Moves) > 1 {
+				s.ponderMove = line.Moves[1]
+			}
+
+			depth = 0 // the mate has been proven, there is nothing left to search
+		} else if print && !s.stop {
+			fmt.Fprintf(s.output, "info string no mate in %d found\n", limits.Mate)
+		}
+	}
+
 	for d := 1; d <= depth; d++ {
+		s.checkPonderHit()
+		if bestMove != chess.NullMove && !s.pondering && !s.timeManager.canStartIteration() {
+			break
+		}
+
 		start := time.Now()
+		s.selDepth = 0
+
+		lines, ok := s.searchLines(&position, d, numLines, alpha, beta)
+		if !ok {
+			if s.stop {
+				break // the iteration did not finish, use the result of the previous one
+			}
 
-		score := s.doSearch(position, alpha, beta, d, 0, 0)
-		if score <= alpha || score >= beta {
+			if print {
+				bound := "upperbound"
+				if lines[0].Score >= beta {
+					bound = "lowerbound"
+				}
+
+				s.printLine(d, 1, lines[0], bound)
+			}
+
+			// the score fell outside of the aspiration window, search again with a full window
 			alpha = initialAlpha
 			beta = initialBeta
 
@@ -74,13 +185,34 @@ func (s *NegamaxSearcher) Search(position chess.Position, depth int, print bool)
 
 		elapsed := time.Since(start)
 
-		bestMove = s.pvtable[0][0]
+		best := lines[0]
+		score := best.Score
+
+		bestMoveChanged := false
+		scoreDrop := 0
+		if bestMove != chess.NullMove {
+			bestMoveChanged = bestMove != best.Moves[0]
+			scoreDrop = bestScore - score
+		}
+
+		s.timeManager.update(elapsed, bestMoveChanged, scoreDrop)
+
+		s.lines = lines
+		bestMove = best.Moves[0]
+		bestScore = score
+
+		s.ponderMove = chess.NullMove
+		if len(best.Moves) > 1 {
+			s.ponderMove = best.Moves[1]
+		}
+
 		alpha = score - window
 		beta = score + window
 
 		if print {
-			nps := float64(s.nodes) / float64(elapsed.Seconds())
-			fmt.Printf("info depth %d score cp %d nodes %d nps %f pv %s time %d tbhits %d\n", d, score, s.nodes, nps, s.getPV(), elapsed.Milliseconds(), s.ttable.Hits())
+			for k, line := range lines[:min(s.multiPV, len(lines))] {
+				s.printLine(d, k+1, line, "")
+			}
 		}
 
 		if s.stop {
@@ -88,25 +220,74 @@ func (s *NegamaxSearcher) Search(position chess.Position, depth int, print bool)
 		}
 	}
 
+	if s.skill.Enabled() && len(s.lines) > 0 {
+		bestMove, s.ponderMove = s.pickSkillMove()
+	}
+
+	if bestMove == chess.NullMove && len(rootMoves) > 0 {
+		bestMove = rootMoves[0]
+	}
+
+	// an infinite search or a search on the opponent's time can only end
+	// once it has been told to stop
+	for (limits.Infinite || s.pondering) && !s.stop {
+		select {
+		case <-s.ctx.Done():
+			break
+		case <-s.ponderHits:
+			break
+		}
+
+		s.checkPonderHit()
+		s.checkCancelled()
+	}
+
+	// only cleared once the search is over so that a ponderhit received
+	// before the search started isn't lost
+	s.ponderHit.Store(false)
+	select {
+	case <-s.ponderHits:
+		break
+	default:
+		break
+	}
+
 	return bestMove
 }
 
-func (s NegamaxSearcher) getPV() string {
-	var builder strings.Builder
+// checkLimits stops the search if any of the search limits have been reached.
+func (s *NegamaxSearcher) checkLimits() {
+	if s.limits.Nodes > 0 && s.nodes >= s.limits.Nodes {
+		s.stop = true
+	}
 
-	length := s.pvlength[0]
-	for i := 0; i < length; i++ {
-		builder.WriteString(s.pvtable[0][i].String())
+	s.checkCancelled()
 
-		if i != length {
-			builder.WriteString(" ")
-		}
+	s.checkPonderHit()
+	if !s.pondering && s.timeManager.hardLimitReached() {
+		s.stop = true
+	}
+
+	s.printProgress()
+}
+
+// checkCancelled stops the search once its context has been cancelled.
+func (s *NegamaxSearcher) checkCancelled() {
+	if s.ctx.Err() != nil {
+		s.stop = true
 	}
+}
 
-	return builder.String()
+// checkPonderHit switches from pondering to a normal timed search once the
+// opponent has played the predicted move.
+func (s *NegamaxSearcher) checkPonderHit() {
+	if s.pondering && s.ponderHit.Load() {
+		s.pondering = false
+		s.timeManager.restart()
+	}
 }
 
-func (s NegamaxSearcher) scoreMove(position chess.Position, move chess.Move, ply int) int {
+func (s *NegamaxSearcher) scoreMove(position chess.Position, move chess.Move, ply int) int {
 	turn := position.Turn()
 
 	if s.pvtable[0][ply] == move {
@@ -120,8 +301,9 @@ func (s NegamaxSearcher) scoreMove(position chess.Position, move chess.Move, ply
 	return 0
 }
 
-func (s *NegamaxSearcher) doSearch(position chess.Position, alpha int, beta int, depth int, ply int, extensions int) int {
+func (s *NegamaxSearcher) doSearch(position *chess.Position, alpha int, beta int, depth int, ply int, extensions int) int {
 	s.pvlength[ply] = ply
+	s.selDepth = max(s.selDepth, ply)
 
 	if s.stop {
 		return 0
@@ -135,6 +317,10 @@ func (s *NegamaxSearcher) doSearch(position chess.Position, alpha int, beta int,
 		return evaluation.DrawScore
 	}
 
+	if ply >= maxPly-1 {
+		return s.evaluator.AbsoluteEvaluation(position)
+	}
+
 	pvNode := beta-alpha != 1
 	inCheck := position.IsKingInCheck(position.Turn())
 
@@ -145,30 +331,39 @@ func (s *NegamaxSearcher) doSearch(position chess.Position, alpha int, beta int,
 
 	if depth == 0 {
 		if inCheck { // don't go in quiescence search when in check
-			return s.evaluator.AbsoluteEvaluation(&position)
+			// the evaluation is only a mate score when there are no legal moves
+			score := s.evaluator.AbsoluteEvaluation(position)
+			if score == -evaluation.MateScore {
+				return score + ply // prefer being mated later
+			}
+
+			return score
 		} else {
-			return s.quiescence(position, alpha, beta)
+			return s.quiescence(position, alpha, beta, ply)
 		}
 	}
 
 	s.nodes++
+	s.checkLimits()
 
 	entry, ok := s.ttable.Get(position.Hash())
 	if ok {
 		if entry.Depth >= depth && entry.Hash == position.Hash() && ply != 0 {
+			score := scoreFromTable(entry.Score, ply)
+
 			switch entry.Type {
 			case ExactNode:
 				s.pvlength[ply] = ply + 1
 				s.pvtable[ply][ply] = entry.Move
-				return entry.Score
+				return score
 			case UpperNode:
-				if entry.Score <= alpha {
+				if score <= alpha {
 					return alpha
 				}
 
 				break
 			case LowerNode:
-				if entry.Score >= beta {
+				if score >= beta {
 					return beta
 				}
 
@@ -184,7 +379,7 @@ func (s *NegamaxSearcher) doSearch(position chess.Position, alpha int, beta int,
 
 		position.MakeNullMove()
 		score := -s.doSearch(position, -beta, -beta+1, depth-1-nullMovePruningReduction, ply+1, extensions)
-		position.Undo()
+		position.UndoInPlace()
 
 		s.drawTable.Pop()
 
@@ -193,21 +388,34 @@ func (s *NegamaxSearcher) doSearch(position chess.Position, alpha int, beta int,
 		}
 	}
 
-	moves := position.GenerateMoves(chess.LegalMoveGeneration)
-	slices.SortFunc(moves, func(m1, m2 chess.Move) int {
-		return cmp.Compare(s.scoreMove(position, m1, ply), s.scoreMove(position, m2, ply))
-	})
+	var moves chess.MoveList
+	position.GenerateMoveList(chess.LegalMoveGeneration, &moves)
+	for i := 0; i < moves.Len(); i++ {
+		moves.SetScore(i, s.scoreMove(*position, moves.Move(i), ply))
+	}
 
 	bestMove := chess.NullMove
 	bestScore := math.MinInt
 	nodeType := UpperNode
 
-	for _, move := range moves {
+	searchedMoves := 0
+	for i := 0; i < moves.Len(); i++ {
+		// the moves are picked best first so the rest don't need ordering after a cutoff
+		move := moves.PickBest(i)
+		if ply == 0 && (slices.Contains(s.excludedMoves, move) || !s.limits.allows(move)) {
+			continue
+		}
+
+		searchedMoves++
+		if ply == 0 {
+			s.printCurrentMove(depth, move, searchedMoves)
+		}
+
 		s.drawTable.Push(position.Hash())
 
 		position.MakeMove(move)
 		score := -s.doSearch(position, -beta, -alpha, depth-1, ply+1, extensions)
-		position.Undo()
+		position.UndoInPlace()
 
 		s.drawTable.Pop()
 
@@ -256,7 +464,7 @@ func (s *NegamaxSearcher) doSearch(position chess.Position, alpha int, beta int,
 		}
 	}
 
-	if len(moves) == 0 {
+	if moves.Len() == 0 {
 		if inCheck {
 			return -evaluation.MateScore + ply
 		}
@@ -264,16 +472,26 @@ func (s *NegamaxSearcher) doSearch(position chess.Position, alpha int, beta int,
 		return evaluation.DrawScore
 	}
 
-	if !s.stop {
-		entry := NewTableEntry(position.Hash(), nodeType, bestMove, bestScore, depth, position.Plies())
+	// searches with excluded or restricted moves don't find the real best move of the position
+	excluded := ply == 0 && (len(s.excludedMoves) > 0 || len(s.limits.SearchMoves) > 0)
+	if !s.stop && !excluded {
+		entry := NewTableEntry(position.Hash(), nodeType, bestMove, scoreToTable(bestScore, ply), depth, position.Plies())
 		s.ttable.Insert(position.Hash(), entry)
 	}
 
 	return bestScore
 }
 
-func (s NegamaxSearcher) quiescence(position chess.Position, alpha int, beta int) int {
-	evaluation := s.evaluator.AbsoluteEvaluation(&position)
+func (s *NegamaxSearcher) quiescence(position *chess.Position, alpha int, beta int, ply int) int {
+	s.nodes++
+	s.selDepth = max(s.selDepth, ply)
+
+	s.checkLimits()
+	if s.stop {
+		return 0
+	}
+
+	evaluation := s.evaluator.AbsoluteEvaluation(position)
 	if evaluation >= beta {
 		return beta
 	}
@@ -282,11 +500,13 @@ func (s NegamaxSearcher) quiescence(position chess.Position, alpha int, beta int
 		alpha = evaluation
 	}
 
-	captures := position.GenerateMoves(chess.CaptureMoveGeneration)
-	for _, capture := range captures {
+	var captures chess.MoveList
+	position.GenerateMoveList(chess.CaptureMoveGeneration, &captures)
+	for i := 0; i < captures.Len(); i++ {
+		capture := captures.Move(i)
 		position.MakeMove(capture)
-		score := -s.quiescence(position, -beta, -alpha)
-		position.Undo()
+		score := -s.quiescence(position, -beta, -alpha, ply+1)
+		position.UndoInPlace()
 
 		if score >= beta {
 			return beta
@@ -300,11 +520,55 @@ func (s NegamaxSearcher) quiescence(position chess.Position, alpha int, beta int
 	return alpha
 }
 
-func (s *NegamaxSearcher) Stop() {
-	s.stop = true
+// SetOutput sets where the information about the search is written to.
+func (s *NegamaxSearcher) SetOutput(output io.Writer) {
+	s.output = output
+}
+
+// SetHashSize changes the size of the transposition table to the given
+// number of megabytes.
+func (s *NegamaxSearcher) SetHashSize(size int) {
+	s.ttable.Resize(size)
 }
 
-func (s NegamaxSearcher) Stopped() bool {
+// ClearHash removes all entries from the transposition table.
+func (s *NegamaxSearcher) ClearHash() {
+	s.ttable.Clear()
+}
+
+// SetShowWDL sets whether the win, draw and loss chances are reported along
+// with the score.
+func (s *NegamaxSearcher) SetShowWDL(show bool) {
+	s.showWDL = show
+}
+
+// SetMoveOverhead sets the amount of time to reserve for each move to make
+// up for delays in communicating with the gui.
+func (s *NegamaxSearcher) SetMoveOverhead(overhead time.Duration) {
+	s.moveOverhead = overhead
+}
+
+// PonderHit tells a search started in ponder mode that the opponent played the
+// predicted move, turning it into a normal search.
+func (s *NegamaxSearcher) PonderHit() {
+	s.ponderHit.Store(true)
+
+	// a search waiting for the ponderhit is woken up, there is no need to queue more than one
+	select {
+	case s.ponderHits <- struct{}{}:
+		break
+	default:
+		break
+	}
+}
+
+// PonderMove returns the move the opponent is expected to reply with to the
+// best move of the last search. If there is no expected reply NullMove is returned.
+func (s *NegamaxSearcher) PonderMove() chess.Move {
+	return s.ponderMove
+}
+
+func (s *NegamaxSearcher) Stopped() bool {
 	return s.stop
 }
 
@@ -317,8 +581,21 @@ func (s *NegamaxSearcher) ClearPreviousSearch() {
 	clear(s.killerMoves)
 	s.killerMoveIndex = 0
 
-	s.pvtable = [MaxDepth][MaxDepth]chess.Move{}
-	s.pvlength = [MaxDepth]int{}
+	s.lines = s.lines[:0]
+	s.excludedMoves = s.excludedMoves[:0]
+
+	s.pvtable = [maxPly][maxPly]chess.Move{}
+	s.pvlength = [maxPly]int{}
+}
+
+// SetHistory replaces the positions known to have been played before the
+// position being searched. These are used to detect repetitions that span
+// moves made before the search started.
+func (s *NegamaxSearcher) SetHistory(hashes []uint64) {
+	s.drawTable.Clear()
+	for _, hash := range hashes {
+		s.drawTable.Push(hash)
+	}
 }
 
 // Reset clears any information about searched positions.
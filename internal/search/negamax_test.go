@@ -1,9 +1,12 @@
 package search
 
 import (
+	"context"
 	"rosaline/internal/chess"
 	"rosaline/internal/evaluation"
+	"slices"
 	"testing"
+	"time"
 )
 
 func BenchmarkNegamax(b *testing.B) {
@@ -11,7 +14,173 @@ func BenchmarkNegamax(b *testing.B) {
 	evaluator := evaluation.NewEvaluator()
 	searcher := NewNegamaxSearcher(evaluator)
 
+	// allocations are reported along with the nodes to show they don't grow with the size of the search
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	nodes := 0
 	for i := 0; i < b.N; i++ {
-		searcher.Search(position, 4, false)
+		// the table is cleared so every search visits the same nodes
+		b.StopTimer()
+		searcher.ClearHash()
+		b.StartTimer()
+
+		searcher.Search(context.Background(), position, NewDepthLimits(4), false)
+		nodes += searcher.nodes
+	}
+
+	b.ReportMetric(float64(nodes)/float64(b.N), "nodes/op")
+}
+
+func TestMultiPV(t *testing.T) {
+	position, _ := chess.NewPosition(chess.StartingFen)
+	evaluator := evaluation.NewEvaluator()
+	searcher := NewNegamaxSearcher(evaluator)
+	searcher.SetMultiPV(3)
+
+	bestMove := searcher.Search(context.Background(), position, NewDepthLimits(2), false)
+
+	lines := searcher.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("%s: expected 3 lines got %d", t.Name(), len(lines))
+	}
+
+	if lines[0].Moves[0] != bestMove {
+		t.Fatalf("%s: expected the first line to start with the best move %s got %s", t.Name(), bestMove, lines[0].Moves[0])
+	}
+
+	seen := map[chess.Move]bool{}
+	for i, line := range lines {
+		if seen[line.Moves[0]] {
+			t.Fatalf("%s: move %s starts more than one line", t.Name(), line.Moves[0])
+		}
+		seen[line.Moves[0]] = true
+
+		if i > 0 && line.Score > lines[i-1].Score {
+			t.Fatalf("%s: line %d has a higher score than the line before it", t.Name(), i+1)
+		}
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	cases := []struct {
+		Score    int
+		Expected string
+	}{
+		{Score: 35, Expected: "cp 35"},
+		{Score: -120, Expected: "cp -120"},
+		{Score: evaluation.MateScore - 1, Expected: "mate 1"},
+		{Score: evaluation.MateScore - 3, Expected: "mate 2"},
+		{Score: -evaluation.MateScore + 2, Expected: "mate -1"},
+		{Score: -evaluation.MateScore + 4, Expected: "mate -2"},
+	}
+
+	for _, c := range cases {
+		formatted := formatScore(c.Score)
+		if formatted != c.Expected {
+			t.Fatalf("%s: expected score %d to be formatted as '%s' got '%s'", t.Name(), c.Score, c.Expected, formatted)
+		}
+	}
+}
+
+func TestMateScore(t *testing.T) {
+	position, _ := chess.NewPosition("7k/8/5KQ1/8/8/8/8/8 w - - 0 1")
+	evaluator := evaluation.NewEvaluator()
+	searcher := NewNegamaxSearcher(evaluator)
+
+	bestMove := searcher.Search(context.Background(), position, NewDepthLimits(3), false)
+	if bestMove.String() != "g6g7" {
+		t.Fatalf("%s: expected mate in one with g6g7 got %s", t.Name(), bestMove)
+	}
+
+	score := searcher.Lines()[0].Score
+	if mateIn(score) != 1 {
+		t.Fatalf("%s: expected a score of mate 1 got %s", t.Name(), formatScore(score))
+	}
+}
+
+func TestSearchMoves(t *testing.T) {
+	position, _ := chess.NewPosition(chess.StartingFen)
+	evaluator := evaluation.NewEvaluator()
+	searcher := NewNegamaxSearcher(evaluator)
+	searcher.SetMultiPV(3)
+
+	searchMoves := []chess.Move{
+		chess.NewMove(chess.B1, chess.A3, chess.QuietMove),
+		chess.NewMove(chess.G1, chess.H3, chess.QuietMove),
+	}
+
+	bestMove := searcher.Search(context.Background(), position, SearchLimits{Depth: 2, SearchMoves: searchMoves}, false)
+	if !slices.Contains(searchMoves, bestMove) {
+		t.Fatalf("%s: expected the best move to be one of the search moves got %s", t.Name(), bestMove)
+	}
+
+	lines := searcher.Lines()
+	if len(lines) != len(searchMoves) {
+		t.Fatalf("%s: expected %d lines got %d", t.Name(), len(searchMoves), len(lines))
+	}
+
+	for _, line := range lines {
+		if !slices.Contains(searchMoves, line.Moves[0]) {
+			t.Fatalf("%s: expected every line to start with a search move got %s", t.Name(), line)
+		}
+	}
+}
+
+func TestSearchWaitsForPonderHit(t *testing.T) {
+	position, _ := chess.NewPosition(chess.StartingFen)
+	searcher := NewNegamaxSearcher(evaluation.NewEvaluator())
+
+	limits := NewDepthLimits(1)
+	limits.Ponder = true
+
+	done := make(chan chess.Move)
+	go func() {
+		done <- searcher.Search(context.Background(), position, limits, false)
+	}()
+
+	// the depth is reached straight away but the move can't be played until the ponderhit
+	select {
+	case <-done:
+		t.Fatalf("%s: expected the search to wait for a ponderhit", t.Name())
+	case <-time.After(50 * time.Millisecond):
+		break
+	}
+
+	searcher.PonderHit()
+
+	select {
+	case move := <-done:
+		if move == chess.NullMove {
+			t.Fatalf("%s: expected a best move", t.Name())
+		}
+		break
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s: expected the search to finish after the ponderhit", t.Name())
+	}
+}
+
+func TestSearchWaitsForCancel(t *testing.T) {
+	position, _ := chess.NewPosition(chess.StartingFen)
+	searcher := NewNegamaxSearcher(evaluation.NewEvaluator())
+
+	limits := NewDepthLimits(1)
+	limits.Infinite = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan chess.Move)
+	go func() {
+		done <- searcher.Search(ctx, position, limits, false)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+		break
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s: expected the search to finish once cancelled", t.Name())
 	}
 }
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"context"
+	"math/rand"
+	"rosaline/internal/chess"
+	"rosaline/internal/evaluation"
+	"testing"
+)
+
+func TestSkillFromElo(t *testing.T) {
+	if SkillFromElo(MaxElo).level >= MaxSkillLevel {
+		t.Fatalf("%s: expected the maximum elo to still limit strength", t.Name())
+	}
+
+	previous := SkillFromElo(MinElo - 100)
+	if previous.level != MinSkillLevel {
+		t.Fatalf("%s: expected elo below the minimum to be the lowest level got %v", t.Name(), previous.level)
+	}
+
+	for _, measured := range skillElo {
+		if skill := SkillFromElo(measured.elo); skill.level != measured.level {
+			t.Fatalf("%s: expected elo %d to be level %v got %v", t.Name(), measured.elo, measured.level, skill.level)
+		}
+	}
+
+	for elo := MinElo; elo <= MaxElo; elo += 100 {
+		skill := SkillFromElo(elo)
+		if skill.level < previous.level {
+			t.Fatalf("%s: expected elo %d to not have a lower level than a lower elo", t.Name(), elo)
+		}
+
+		previous = skill
+	}
+}
+
+func TestSkillEnabled(t *testing.T) {
+	if FullStrength().Enabled() {
+		t.Fatalf("%s: expected full strength to not limit the search", t.Name())
+	}
+
+	if !NewSkill(10).Enabled() {
+		t.Fatalf("%s: expected skill level 10 to limit the search", t.Name())
+	}
+}
+
+func TestSkillLimit(t *testing.T) {
+	skill := NewSkill(0)
+
+	limits := skill.limit(NewDepthLimits(10))
+	if limits.Depth != skill.depth() || limits.Nodes != skill.nodes() {
+		t.Fatalf("%s: expected limits of depth %d and %d nodes got %+v", t.Name(), skill.depth(), skill.nodes(), limits)
+	}
+
+	limits = skill.limit(SearchLimits{Depth: 1, Nodes: 10})
+	if limits.Depth != 1 || limits.Nodes != 10 {
+		t.Fatalf("%s: expected stricter limits to be kept got %+v", t.Name(), limits)
+	}
+}
+
+func TestSkillPick(t *testing.T) {
+	lines := []SearchLine{
+		{Score: 50, Moves: []chess.Move{chess.NewMove(chess.E2, chess.E4, chess.QuietMove)}},
+		{Score: 40, Moves: []chess.Move{chess.NewMove(chess.D2, chess.D4, chess.QuietMove)}},
+		{Score: -500, Moves: []chess.Move{chess.NewMove(chess.F2, chess.F3, chess.QuietMove)}},
+	}
+
+	random := rand.New(rand.NewSource(1))
+	picked := map[chess.Move]int{}
+	for i := 0; i < 100; i++ {
+		line := NewSkill(MinSkillLevel).pick(lines, random)
+		picked[line.Moves[0]]++
+	}
+
+	if picked[lines[1].Moves[0]] == 0 {
+		t.Fatalf("%s: expected the lowest skill level to sometimes pick the second best line", t.Name())
+	}
+
+	for i := 0; i < 100; i++ {
+		line := NewSkill(MaxSkillLevel-1).pick(lines, random)
+		if line.Moves[0] == lines[2].Moves[0] {
+			t.Fatalf("%s: expected a high skill level to never pick a line that loses material", t.Name())
+		}
+	}
+}
+
+func TestLimitedStrengthSearch(t *testing.T) {
+	position, _ := chess.NewPosition(chess.StartingFen)
+	searcher := NewNegamaxSearcher(evaluation.NewEvaluator())
+	searcher.SetSkill(NewSkill(0))
+
+	bestMove := searcher.Search(context.Background(), position, NewDepthLimits(MaxDepth), false)
+	if bestMove == chess.NullMove {
+		t.Fatalf("%s: expected a move to be found", t.Name())
+	}
+
+	if len(searcher.Lines()) != skillCandidates {
+		t.Fatalf("%s: expected %d lines to choose from got %d", t.Name(), skillCandidates, len(searcher.Lines()))
+	}
+}
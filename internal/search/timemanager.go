@@ -0,0 +1,153 @@
+package search
+
+import (
+	"rosaline/internal/chess"
+	"time"
+)
+
+const (
+	DefaultMoveOverhead = 30 * time.Millisecond
+
+	defaultMovesToGo = 30 // The number of moves assumed to be left when playing with sudden death time controls.
+	maxMovesToGo     = 50
+
+	hardLimitFactor = 5   // The hard limit is at most this many times the soft limit.
+	maxTimeRatio    = 0.8 // The hard limit is at most this portion of the remaining time.
+
+	minBranchingFactor     = 1.5
+	maxBranchingFactor     = 6.0
+	defaultBranchingFactor = 2.0
+
+	instabilityExtension = 0.5 // How much the soft limit is extended by when the best move changes.
+	scoreDropExtension   = 0.5 // How much the soft limit is extended by for a large drop in score.
+	scoreDropMargin      = 100 // The drop in score that will receive the full extension.
+	maxExtension         = 3.0
+)
+
+// timeManager decides how much time a search is allowed to use.
+//
+// It uses two budgets:
+//   - soft: the search should not start a new iteration past this point.
+//   - hard: the search has to stop as soon as possible past this point.
+type timeManager struct {
+	start time.Time
+
+	soft time.Duration
+	hard time.Duration
+
+	limited bool // Whether the search has a time limit at all.
+	fixed   bool // Whether the search time is fixed and can't be extended.
+
+	instability float64 // How often the best move has changed recently.
+	scale       float64 // The amount the soft limit is currently extended by.
+
+	lastIteration     time.Duration // The duration of the last completed iteration.
+	previousIteration time.Duration // The duration of the iteration before the last one.
+}
+
+// newTimeManager creates a timeManager that allocates time for the given color.
+func newTimeManager(limits SearchLimits, turn chess.Color, overhead time.Duration) timeManager {
+	tm := timeManager{
+		start: time.Now(),
+		scale: 1,
+	}
+
+	if limits.Infinite {
+		return tm
+	}
+
+	if limits.MoveTime > 0 {
+		tm.limited = true
+		tm.fixed = true
+		tm.soft = max(limits.MoveTime-overhead, time.Millisecond)
+		tm.hard = tm.soft
+		return tm
+	}
+
+	remaining, increment := limits.clock(turn)
+	if remaining <= 0 {
+		return tm
+	}
+
+	movesToGo := limits.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+	movesToGo = min(movesToGo, maxMovesToGo)
+
+	available := max(remaining-overhead, time.Millisecond)
+
+	tm.limited = true
+	tm.hard = min(time.Duration(float64(available)*maxTimeRatio), available/time.Duration(movesToGo)*hardLimitFactor+increment)
+	tm.soft = min(available/time.Duration(movesToGo)+increment*3/4, tm.hard)
+
+	return tm
+}
+
+// restart starts the clock over from the current time.
+func (tm *timeManager) restart() {
+	tm.start = time.Now()
+}
+
+// elapsed returns how long the search has been running.
+func (tm timeManager) elapsed() time.Duration {
+	return time.Since(tm.start)
+}
+
+// hardLimitReached returns whether the search needs to stop immediately.
+func (tm timeManager) hardLimitReached() bool {
+	return tm.limited && tm.elapsed() >= tm.hard
+}
+
+// canStartIteration returns whether there is likely to be enough time to
+// finish another iteration of the search.
+func (tm timeManager) canStartIteration() bool {
+	if !tm.limited {
+		return true
+	}
+
+	elapsed := tm.elapsed()
+
+	soft := time.Duration(float64(tm.soft) * tm.scale)
+	if elapsed >= min(soft, tm.hard) {
+		return false
+	}
+
+	// estimate how long the next iteration will take from how much the
+	// previous iterations grew by
+	branchingFactor := defaultBranchingFactor
+	if tm.previousIteration > 0 {
+		branchingFactor = float64(tm.lastIteration) / float64(tm.previousIteration)
+		branchingFactor = min(max(branchingFactor, minBranchingFactor), maxBranchingFactor)
+	}
+
+	predicted := time.Duration(float64(tm.lastIteration) * branchingFactor)
+	return elapsed+predicted < tm.hard
+}
+
+// update records the result of a completed iteration and extends the soft
+// limit if the search is unstable.
+//
+// scoreDrop is how much the score has fallen since the previous iteration.
+func (tm *timeManager) update(duration time.Duration, bestMoveChanged bool, scoreDrop int) {
+	tm.previousIteration = tm.lastIteration
+	tm.lastIteration = duration
+
+	if tm.fixed {
+		return
+	}
+
+	tm.instability /= 2
+	if bestMoveChanged {
+		tm.instability++
+	}
+
+	tm.scale = 1 + tm.instability*instabilityExtension
+
+	if scoreDrop > 0 {
+		drop := float64(min(scoreDrop, scoreDropMargin)) / scoreDropMargin
+		tm.scale *= 1 + drop*scoreDropExtension
+	}
+
+	tm.scale = min(tm.scale, maxExtension)
+}
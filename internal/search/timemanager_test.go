@@ -0,0 +1,101 @@
+package search
+
+import (
+	"rosaline/internal/chess"
+	"testing"
+	"time"
+)
+
+func TestMoveTimeAllocation(t *testing.T) {
+	limits := SearchLimits{MoveTime: time.Second}
+	tm := newTimeManager(limits, chess.White, 50*time.Millisecond)
+
+	expected := 950 * time.Millisecond
+	if tm.soft != expected || tm.hard != expected {
+		t.Fatalf("%s: expected soft and hard limits of '%v' got '%v' and '%v'", t.Name(), expected, tm.soft, tm.hard)
+	}
+
+	tm.update(100*time.Millisecond, true, 200)
+	if tm.scale != 1 {
+		t.Fatalf("%s: expected fixed time to not be extended but got a scale of '%v'", t.Name(), tm.scale)
+	}
+}
+
+func TestClockAllocation(t *testing.T) {
+	cases := []SearchLimits{
+		{WhiteTime: time.Minute, BlackTime: time.Second},
+		{WhiteTime: time.Minute, WhiteIncrement: time.Second},
+		{WhiteTime: 10 * time.Second, MovesToGo: 1},
+		{WhiteTime: 100 * time.Millisecond, WhiteIncrement: time.Second},
+	}
+
+	for _, limits := range cases {
+		tm := newTimeManager(limits, chess.White, DefaultMoveOverhead)
+		if !tm.limited {
+			t.Fatalf("%s: expected search to be limited for %+v", t.Name(), limits)
+		}
+
+		if tm.soft > tm.hard {
+			t.Fatalf("%s: soft limit '%v' is larger than the hard limit '%v' for %+v", t.Name(), tm.soft, tm.hard, limits)
+		}
+
+		if tm.hard >= limits.WhiteTime {
+			t.Fatalf("%s: hard limit '%v' uses all of the remaining time for %+v", t.Name(), tm.hard, limits)
+		}
+	}
+}
+
+func TestUnlimitedAllocation(t *testing.T) {
+	cases := []SearchLimits{
+		{Infinite: true, WhiteTime: time.Second},
+		{BlackTime: time.Second},
+		{Depth: 4},
+	}
+
+	for _, limits := range cases {
+		tm := newTimeManager(limits, chess.White, DefaultMoveOverhead)
+		if tm.limited || tm.hardLimitReached() || !tm.canStartIteration() {
+			t.Fatalf("%s: expected search to not be time limited for %+v", t.Name(), limits)
+		}
+	}
+}
+
+func TestInstabilityExtension(t *testing.T) {
+	limits := SearchLimits{WhiteTime: time.Minute}
+	tm := newTimeManager(limits, chess.White, DefaultMoveOverhead)
+
+	tm.update(time.Millisecond, false, 0)
+	if tm.scale != 1 {
+		t.Fatalf("%s: expected a stable search to have a scale of 1 got '%v'", t.Name(), tm.scale)
+	}
+
+	tm.update(time.Millisecond, true, 0)
+	changed := tm.scale
+	if changed <= 1 {
+		t.Fatalf("%s: expected a best move change to extend the time but got a scale of '%v'", t.Name(), changed)
+	}
+
+	tm.update(time.Millisecond, true, scoreDropMargin)
+	if tm.scale <= changed {
+		t.Fatalf("%s: expected a score drop to extend the time further but got a scale of '%v'", t.Name(), tm.scale)
+	}
+
+	if tm.scale > maxExtension {
+		t.Fatalf("%s: scale '%v' is larger than the maximum extension", t.Name(), tm.scale)
+	}
+}
+
+func TestCanStartIteration(t *testing.T) {
+	limits := SearchLimits{MoveTime: time.Second}
+	tm := newTimeManager(limits, chess.White, 0)
+
+	tm.update(100*time.Millisecond, false, 0)
+	if !tm.canStartIteration() {
+		t.Fatalf("%s: expected to have time for another iteration", t.Name())
+	}
+
+	tm.update(900*time.Millisecond, false, 0)
+	if tm.canStartIteration() {
+		t.Fatalf("%s: expected an iteration predicted to run past the hard limit to not be started", t.Name())
+	}
+}
@@ -3,6 +3,7 @@ package search
 import (
 	"fmt"
 	"rosaline/internal/chess"
+	"rosaline/internal/evaluation"
 	"unsafe"
 )
 
@@ -41,9 +42,12 @@ var emptyEntry = TableEntry{}
 const (
 	entrySize = int(unsafe.Sizeof(emptyEntry))
 
-	kb           = 1024
-	mb           = kb * kb
-	maxTableSize = (64 * mb) / entrySize
+	kb = 1024
+	mb = kb * kb
+
+	DefaultTableSize = 64   // The default size of the table in megabytes.
+	MinTableSize     = 1    // The minimum size of the table in megabytes.
+	MaxTableSize     = 4096 // The maximum size of the table in megabytes.
 )
 
 // NewTableEntry creates a new TableEntry.
@@ -58,42 +62,93 @@ func NewTableEntry(hash uint64, nodeType NodeType, move chess.Move, score, depth
 	}
 }
 
+// scoreToTable converts a mate score relative to the root into one relative
+// to the position being stored so it stays correct when found at a different ply.
+func scoreToTable(score int, ply int) int {
+	if !evaluation.IsMateScore(score) {
+		return score
+	}
+
+	if score > 0 {
+		return score + ply
+	}
+
+	return score - ply
+}
+
+// scoreFromTable converts a mate score stored in the table back into one
+// relative to the root.
+func scoreFromTable(score int, ply int) int {
+	if !evaluation.IsMateScore(score) {
+		return score
+	}
+
+	if score > 0 {
+		return score - ply
+	}
+
+	return score + ply
+}
+
 func (e TableEntry) String() string {
 	return fmt.Sprintf("<Entry: type: %s move: %s score: %d depth: %d>", e.Type, e.Move, e.Score, e.Depth)
 }
 
+// TranspositionTable is a fixed size table of entries. Each hash has one slot
+// in the table and a new entry always replaces the one in its slot.
 type TranspositionTable struct {
-	table  map[uint64]TableEntry
-	hits   int
-	misses int
+	entries []TableEntry
+	used    int
+	hits    int
+	misses  int
 }
 
-// NewTranspositionTable creates a new TranspositionTable.
-func NewTranspositionTable() TranspositionTable {
+// NewTranspositionTable creates a new TranspositionTable that uses the given
+// number of megabytes.
+func NewTranspositionTable(size int) TranspositionTable {
 	return TranspositionTable{
-		table:  make(map[uint64]TableEntry),
-		hits:   0,
-		misses: 0,
+		entries: make([]TableEntry, tableEntries(size)),
+		used:    0,
+		hits:    0,
+		misses:  0,
 	}
 }
 
-// Insert adds a new entry to the table.
+// tableEntries returns the number of entries that fit in the given number of megabytes.
+func tableEntries(size int) int {
+	size = min(max(size, MinTableSize), MaxTableSize)
+	return (size * mb) / entrySize
+}
+
+// index returns the slot in the table for the given hash.
+func (t TranspositionTable) index(hash uint64) int {
+	return int(hash % uint64(len(t.entries)))
+}
+
+// Insert adds a new entry to the table, replacing the entry in its slot.
 func (t *TranspositionTable) Insert(hash uint64, entry TableEntry) {
-	if len(t.table) >= maxTableSize { // TODO: look into replacing old positions instead of clearing table
-		clear(t.table)
+	index := t.index(hash)
+	if t.entries[index] == emptyEntry {
+		t.used++
 	}
 
-	t.table[hash] = entry
+	entry.Hash = hash
+	t.entries[index] = entry
 }
 
 // Remove removes an entry from the table.
 func (t *TranspositionTable) Remove(hash uint64) {
-	delete(t.table, hash)
+	index := t.index(hash)
+	if t.entries[index].Hash == hash && t.entries[index] != emptyEntry {
+		t.entries[index] = emptyEntry
+		t.used--
+	}
 }
 
 // Get retreives the entry that corresponds to the given hash.
 func (t *TranspositionTable) Get(hash uint64) (TableEntry, bool) {
-	value, ok := t.table[hash]
+	entry := t.entries[t.index(hash)]
+	ok := entry.Hash == hash && entry != emptyEntry
 
 	if ok {
 		t.hits++
@@ -101,12 +156,17 @@ func (t *TranspositionTable) Get(hash uint64) (TableEntry, bool) {
 		t.misses++
 	}
 
-	return value, ok
+	return entry, ok
 }
 
-// Size returns the size of the table.
+// Size returns the number of entries in the table.
 func (t TranspositionTable) Size() int {
-	return len(t.table)
+	return t.used
+}
+
+// Hashfull returns how full the table is in permille.
+func (t TranspositionTable) Hashfull() int {
+	return t.used * 1000 / len(t.entries)
 }
 
 // Hits returns the number times a position has been found in the table.
@@ -126,15 +186,19 @@ func (t TranspositionTable) List() {
 		LowerNode: 0,
 	}
 
-	for hash, entry := range t.table {
-		fmt.Printf("%d: %s\n", hash, entry)
+	for _, entry := range t.entries {
+		if entry == emptyEntry {
+			continue
+		}
+
+		fmt.Printf("%d: %s\n", entry.Hash, entry)
 		entries[entry.Type]++
 	}
 
 	for key, value := range entries {
 		fmt.Printf("%s: %d\n", key, value)
 	}
-	fmt.Println("# of entries:", len(t.table))
+	fmt.Println("# of entries:", t.used)
 }
 
 // ResetCounters resets the hits and misses counters.
@@ -143,8 +207,21 @@ func (t *TranspositionTable) ResetCounters() {
 	t.misses = 0
 }
 
+// Resize changes the size of the table to the given number of megabytes.
+//
+// All of the entries in the table are removed.
+func (t *TranspositionTable) Resize(size int) {
+	t.entries = make([]TableEntry, tableEntries(size))
+	t.used = 0
+	t.ResetCounters()
+}
+
 // Clear clears the table and resets the hits and misses counters.
 func (t *TranspositionTable) Clear() {
-	clear(t.table)
+	if t.used > 0 {
+		clear(t.entries)
+		t.used = 0
+	}
+
 	t.ResetCounters()
 }
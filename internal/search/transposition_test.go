@@ -0,0 +1,54 @@
+package search
+
+import (
+	"rosaline/internal/chess"
+	"testing"
+)
+
+func TestTableSize(t *testing.T) {
+	table := NewTranspositionTable(1)
+
+	expected := mb / entrySize
+	if len(table.entries) != expected {
+		t.Fatalf("%s: expected %d entries in 1 megabyte got %d", t.Name(), expected, len(table.entries))
+	}
+}
+
+func TestTableInsert(t *testing.T) {
+	table := NewTranspositionTable(1)
+
+	table.Insert(hash, NewTableEntry(hash, ExactNode, chess.NullMove, 10, 3, 0))
+	entry, ok := table.Get(hash)
+	if !ok || entry.Score != 10 {
+		t.Fatalf("%s: expected an entry with score 10 got %s (found: %v)", t.Name(), entry, ok)
+	}
+
+	// a different position using the same slot replaces the entry
+	other := hash + uint64(len(table.entries))
+	table.Insert(other, NewTableEntry(other, LowerNode, chess.NullMove, 20, 1, 0))
+
+	if _, ok := table.Get(hash); ok {
+		t.Fatalf("%s: expected the entry to be replaced", t.Name())
+	}
+
+	if table.Size() != 1 {
+		t.Fatalf("%s: expected 1 entry got %d", t.Name(), table.Size())
+	}
+}
+
+func TestTableHashfull(t *testing.T) {
+	table := NewTranspositionTable(1)
+
+	for i := 0; i < len(table.entries)/2; i++ {
+		table.Insert(uint64(i), NewTableEntry(uint64(i), ExactNode, chess.NullMove, 0, 1, 0))
+	}
+
+	if table.Hashfull() != 500 {
+		t.Fatalf("%s: expected hashfull of 500 got %d", t.Name(), table.Hashfull())
+	}
+
+	table.Clear()
+	if table.Hashfull() != 0 {
+		t.Fatalf("%s: expected hashfull of 0 after clearing got %d", t.Name(), table.Hashfull())
+	}
+}
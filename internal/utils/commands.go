@@ -3,7 +3,7 @@ package utils
 import "strings"
 
 func ParseCommand(line string) (string, []string) {
-	parts := strings.Split(line, " ")
+	parts := strings.Fields(line)
 	if len(parts) < 1 {
 		return "", []string{}
 	}